@@ -0,0 +1,62 @@
+package agent_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAgent(t *testing.T, timeout time.Duration) *agent.Client {
+	dir, err := ioutil.TempDir("", "agent")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", path)
+	require.Nil(t, err)
+	t.Cleanup(func() { l.Close() })
+	go http.Serve(l, &agent.Server{Timeout: timeout})
+	return &agent.Client{Path: path}
+}
+
+func TestAgent(t *testing.T) {
+	c := newTestAgent(t, time.Hour)
+	_, err := c.Get("a")
+	assert.Equal(t, agent.ErrNotFound, err)
+	require.Nil(t, c.Add("a", []byte("secret a"), 0))
+	require.Nil(t, c.Add("b", []byte("secret b"), time.Minute))
+	secret, err := c.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, []byte("secret a"), secret)
+	entries, err := c.List()
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[0].ID)
+	assert.Equal(t, "a", entries[1].ID)
+	require.Nil(t, c.Remove("b"))
+	assert.Equal(t, agent.ErrNotFound, c.Remove("b"))
+	require.Nil(t, c.Lock())
+	_, err = c.Get("a")
+	assert.Equal(t, agent.ErrNotFound, err)
+	assert.NotNil(t, c.Add("c", nil, 0))
+}
+
+func TestAgentExpiry(t *testing.T) {
+	c := newTestAgent(t, time.Hour)
+	require.Nil(t, c.Add("a", []byte("secret"), 50*time.Millisecond))
+	_, err := c.Get("a")
+	require.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = c.Get("a")
+	assert.Equal(t, agent.ErrNotFound, err)
+	entries, err := c.List()
+	require.Nil(t, err)
+	assert.Empty(t, entries)
+}
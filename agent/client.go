@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Client talks to an agent listening on the Unix socket at Path.
+type Client struct {
+	Path   string
+	client *http.Client
+}
+
+func (c *Client) init() {
+	if c.client != nil {
+		return
+	}
+	c.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", c.Path)
+		},
+	}}
+}
+
+func (c *Client) send(path string, body, result interface{}) (err error) {
+	c.init()
+	var buf bytes.Buffer
+	if err = json.NewEncoder(&buf).Encode(body); err != nil {
+		return
+	}
+	resp, err := c.client.Post("http://unix"+path, "application/json", &buf)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		var v errorResponse
+		if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			return
+		}
+		return errors.New(v.Error)
+	}
+	if result == nil {
+		return
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Add gives the agent a secret to hold for timeout, or for the agent's
+// default timeout if zero. A secret already held under id is replaced.
+func (c *Client) Add(id string, secret []byte, timeout time.Duration) error {
+	return c.send("/add", &addRequest{ID: id, Secret: secret, Timeout: timeout}, nil)
+}
+
+// Get returns the secret held under id, or ErrNotFound.
+func (c *Client) Get(id string) (secret []byte, err error) {
+	var v getResponse
+	err = c.send("/get", &idRequest{ID: id}, &v)
+	return v.Secret, err
+}
+
+// Remove makes the agent forget the secret held under id.
+func (c *Client) Remove(id string) error {
+	return c.send("/remove", &idRequest{ID: id}, nil)
+}
+
+// List returns the secrets held by the agent, soonest to expire first.
+func (c *Client) List() (entries []Entry, err error) {
+	var v listResponse
+	err = c.send("/list", struct{}{}, &v)
+	return v.Entries, err
+}
+
+// Lock makes the agent forget every secret.
+func (c *Client) Lock() error {
+	return c.send("/lock", struct{}{}, nil)
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Server holds secrets until they expire or are removed.
+type Server struct {
+	// Timeout is how long a secret is held if its client gives no
+	// timeout of its own.
+	Timeout time.Duration
+	mu      sync.Mutex
+	secrets map[string]*secret
+}
+
+type secret struct {
+	data    []byte
+	expires time.Time
+	timer   *time.Timer
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var (
+		resp interface{}
+		err  error
+	)
+	switch r.URL.Path {
+	case "/add":
+		var req addRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err == nil {
+			err = s.add(&req)
+		}
+	case "/get":
+		var req idRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err == nil {
+			resp, err = s.get(req.ID)
+		}
+	case "/remove":
+		var req idRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err == nil {
+			err = s.remove(req.ID)
+		}
+	case "/list":
+		resp = s.list()
+	case "/lock":
+		s.lock()
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err == ErrNotFound {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if resp == nil {
+		resp = struct{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
+
+func (s *Server) add(req *addRequest) (err error) {
+	if req.ID == "" || len(req.Secret) == 0 {
+		return errors.New("agent: missing id or secret")
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = s.Timeout
+	}
+	if timeout <= 0 {
+		return errors.New("agent: missing timeout")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.secrets == nil {
+		s.secrets = make(map[string]*secret)
+	}
+	s.removeLocked(req.ID)
+	sec := &secret{data: req.Secret, expires: time.Now().Add(timeout)}
+	sec.timer = time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.secrets[req.ID] == sec {
+			s.removeLocked(req.ID)
+		}
+	})
+	s.secrets[req.ID] = sec
+	return
+}
+
+func (s *Server) get(id string) (resp *getResponse, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sec, ok := s.secrets[id]
+	if !ok || !time.Now().Before(sec.expires) {
+		return nil, ErrNotFound
+	}
+	return &getResponse{Secret: sec.data}, nil
+}
+
+func (s *Server) remove(id string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.removeLocked(id) {
+		err = ErrNotFound
+	}
+	return
+}
+
+// removeLocked forgets a secret, overwriting its data.
+func (s *Server) removeLocked(id string) bool {
+	sec, ok := s.secrets[id]
+	if !ok {
+		return false
+	}
+	sec.timer.Stop()
+	for i := range sec.data {
+		sec.data[i] = 0
+	}
+	delete(s.secrets, id)
+	return true
+}
+
+func (s *Server) list() (resp *listResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp = &listResponse{Entries: []Entry{}}
+	now := time.Now()
+	for id, sec := range s.secrets {
+		if now.Before(sec.expires) {
+			resp.Entries = append(resp.Entries, Entry{ID: id, Expires: sec.expires})
+		}
+	}
+	sort.Slice(resp.Entries, func(i, j int) bool {
+		return resp.Entries[i].Expires.Before(resp.Entries[j].Expires)
+	})
+	return
+}
+
+// lock forgets every secret.
+func (s *Server) lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.secrets {
+		s.removeLocked(id)
+	}
+}
@@ -0,0 +1,42 @@
+// Package agent keeps decrypted secrets, such as wallet seeds, in memory
+// for a limited time, in the manner of ssh-agent. A Server holds the
+// secrets and serves them over a Unix socket to a Client, so that a
+// password need not be entered, nor a key derived, for every command.
+package agent
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned for a secret which the agent does not hold, or
+// which has expired.
+var ErrNotFound = errors.New("agent: secret not found")
+
+// Entry describes a secret held by the agent.
+type Entry struct {
+	ID      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+}
+
+type addRequest struct {
+	ID      string        `json:"id"`
+	Secret  []byte        `json:"secret"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+type idRequest struct {
+	ID string `json:"id"`
+}
+
+type getResponse struct {
+	Secret []byte `json:"secret"`
+}
+
+type listResponse struct {
+	Entries []Entry `json:"entries"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
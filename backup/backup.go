@@ -0,0 +1,218 @@
+// Package backup reads and writes encrypted backups of wallets. A backup
+// holds the seed or mnemonic of every wallet along with its accounts and
+// settings, independently of the layout of the config file, sealed in a
+// keystore envelope under a password and checksummed so that corruption
+// is detected even without the password.
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hectorchu/gonano/keystore"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Format identifies a backup file.
+const Format = "gonano-backup"
+
+// Version is the current version of the backup format.
+const Version = 1
+
+// Wallet types.
+const (
+	Seed   = "seed"
+	Bip39  = "bip39"
+	Ledger = "ledger"
+	Remote = "remote"
+)
+
+// Backup is the content of a backup file.
+type Backup struct {
+	Created  time.Time  `json:"created"`
+	Wallets  []*Wallet  `json:"wallets"`
+	Contacts []*Contact `json:"contacts,omitempty"`
+}
+
+// Wallet is a backed up wallet. Seed is set for regular wallets, and
+// Mnemonic and Passphrase for bip39 wallets. Ledger wallets have neither,
+// and remote wallets give their sign server instead.
+type Wallet struct {
+	Name           string     `json:"name,omitempty"`
+	Type           string     `json:"type"`
+	Seed           string     `json:"seed,omitempty"`
+	Mnemonic       string     `json:"mnemonic,omitempty"`
+	Passphrase     string     `json:"passphrase,omitempty"`
+	Representative string     `json:"representative,omitempty"`
+	Signer         *Signer    `json:"signer,omitempty"`
+	Accounts       []*Account `json:"accounts"`
+}
+
+// Signer is the sign server of a remote wallet.
+type Signer struct {
+	URL    string `json:"url"`
+	Wallet string `json:"wallet"`
+	Cert   string `json:"cert,omitempty"`
+	Key    string `json:"key,omitempty"`
+	CA     string `json:"ca,omitempty"`
+}
+
+// Account is an account of a backed up wallet.
+type Account struct {
+	Index   uint32 `json:"index"`
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	Hidden  bool   `json:"hidden,omitempty"`
+}
+
+// Contact is an entry of the address book.
+type Contact struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// File is a backup file. Checksum is the SHA-256 hash of the JSON encoding
+// of Envelope.
+type File struct {
+	Format   string             `json:"format"`
+	Version  int                `json:"version"`
+	Checksum string             `json:"checksum"`
+	Envelope *keystore.Envelope `json:"envelope"`
+}
+
+// Write encrypts b under password, deriving the key with the named key
+// derivation function, and writes it as a backup file.
+func Write(w io.Writer, b *Backup, password []byte, kdf string) (err error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	f := &File{Format: Format, Version: Version}
+	if f.Envelope, err = keystore.Seal(data, password, kdf); err != nil {
+		return
+	}
+	if f.Checksum, err = checksum(f.Envelope); err != nil {
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// Read reads a backup file and verifies its checksum.
+func Read(r io.Reader) (f *File, err error) {
+	f = new(File)
+	if err = json.NewDecoder(r).Decode(f); err != nil {
+		return nil, fmt.Errorf("backup: %v", err)
+	}
+	if f.Format != Format {
+		return nil, errors.New("backup: not a backup file")
+	}
+	if f.Version > Version {
+		return nil, fmt.Errorf("backup: unsupported version %d", f.Version)
+	}
+	if f.Envelope == nil {
+		return nil, errors.New("backup: missing envelope")
+	}
+	sum, err := checksum(f.Envelope)
+	if err != nil {
+		return
+	}
+	if sum != f.Checksum {
+		return nil, errors.New("backup: checksum mismatch")
+	}
+	return
+}
+
+// Open decrypts the backup with password and verifies its content.
+func (f *File) Open(password []byte) (b *Backup, err error) {
+	data, err := f.Envelope.Open(password)
+	if err != nil {
+		return
+	}
+	b = new(Backup)
+	if err = json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("backup: %v", err)
+	}
+	if err = b.Verify(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Verify checks that every wallet of the backup is complete, and that the
+// accounts of wallets with seeds are derived from them.
+func (b *Backup) Verify() (err error) {
+	for i, w := range b.Wallets {
+		if err = w.verify(); err != nil {
+			return fmt.Errorf("backup: wallet %d: %v", i, err)
+		}
+	}
+	return
+}
+
+func (w *Wallet) verify() (err error) {
+	switch w.Type {
+	case Seed, Bip39:
+	case Ledger:
+		return
+	case Remote:
+		if w.Signer == nil || w.Signer.URL == "" {
+			return errors.New("missing sign server")
+		}
+		return
+	default:
+		return fmt.Errorf("unknown type %q", w.Type)
+	}
+	w2, err := w.Open()
+	if err != nil {
+		return
+	}
+	for _, a := range w.Accounts {
+		index := a.Index
+		a2, err := w2.NewAccount(&index)
+		if err != nil {
+			return err
+		}
+		if a2.Address() != a.Address {
+			return fmt.Errorf("account %d is %s, not %s", a.Index, a2.Address(), a.Address)
+		}
+	}
+	return
+}
+
+// Open returns the wallet.Wallet of a wallet with a seed.
+func (w *Wallet) Open() (w2 *wallet.Wallet, err error) {
+	switch w.Type {
+	case Seed:
+		seed, err := hex.DecodeString(w.Seed)
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != 32 {
+			return nil, errors.New("invalid seed length")
+		}
+		return wallet.NewWallet(seed)
+	case Bip39:
+		if !bip39.IsMnemonicValid(w.Mnemonic) {
+			return nil, errors.New("invalid mnemonic")
+		}
+		return wallet.NewBip39Wallet(w.Mnemonic, w.Passphrase)
+	}
+	return nil, fmt.Errorf("%s wallet has no seed", w.Type)
+}
+
+func checksum(e *keystore.Envelope) (sum string, err error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
@@ -0,0 +1,88 @@
+package backup_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/backup"
+	"github.com/hectorchu/gonano/keystore"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMnemonic = "edge defense waste choose enrich upon flee junk siren film clown finish luggage leader kid quick brick print evidence swap drill paddle truly occur"
+
+func newTestBackup(t *testing.T) *backup.Backup {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := wallet.NewWallet(seed)
+	require.Nil(t, err)
+	index := uint32(3)
+	a, err := w.NewAccount(&index)
+	require.Nil(t, err)
+	w2, err := wallet.NewBip39Wallet(testMnemonic, "some password")
+	require.Nil(t, err)
+	a2, err := w2.NewAccount(nil)
+	require.Nil(t, err)
+	return &backup.Backup{
+		Created: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		Wallets: []*backup.Wallet{{
+			Name:     "savings",
+			Type:     backup.Seed,
+			Seed:     hex.EncodeToString(seed),
+			Accounts: []*backup.Account{{Index: 3, Address: a.Address(), Label: "rent", Hidden: true}},
+		}, {
+			Type:           backup.Bip39,
+			Mnemonic:       testMnemonic,
+			Passphrase:     "some password",
+			Representative: a.Address(),
+			Accounts:       []*backup.Account{{Index: 0, Address: a2.Address()}},
+		}, {
+			Type:     backup.Ledger,
+			Accounts: []*backup.Account{{Index: 0, Address: a.Address()}},
+		}},
+		Contacts: []*backup.Contact{{Name: "alice", Address: a2.Address()}},
+	}
+}
+
+func TestBackup(t *testing.T) {
+	b := newTestBackup(t)
+	var buf bytes.Buffer
+	require.Nil(t, backup.Write(&buf, b, []byte("pw"), keystore.Argon2id))
+	f, err := backup.Read(bytes.NewReader(buf.Bytes()))
+	require.Nil(t, err)
+	_, err = f.Open([]byte("wrong"))
+	assert.Equal(t, keystore.ErrInvalidPassword, err)
+	b2, err := f.Open([]byte("pw"))
+	require.Nil(t, err)
+	assert.Equal(t, b, b2)
+}
+
+func TestBackupChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, backup.Write(&buf, newTestBackup(t), []byte("pw"), keystore.Argon2id))
+	corrupted := strings.Replace(buf.String(), `"ciphertext": "`, `"ciphertext": "00`, 1)
+	_, err := backup.Read(strings.NewReader(corrupted))
+	assert.EqualError(t, err, "backup: checksum mismatch")
+	_, err = backup.Read(strings.NewReader(`{"format":"other"}`))
+	assert.EqualError(t, err, "backup: not a backup file")
+}
+
+func TestBackupVerify(t *testing.T) {
+	b := newTestBackup(t)
+	require.Nil(t, b.Verify())
+	b.Wallets[0].Accounts[0].Index = 4
+	assert.Error(t, b.Verify())
+	b = newTestBackup(t)
+	b.Wallets[1].Passphrase = ""
+	assert.Error(t, b.Verify())
+	b = newTestBackup(t)
+	b.Wallets[0].Seed = "00"
+	assert.EqualError(t, b.Verify(), "backup: wallet 0: invalid seed length")
+	b = newTestBackup(t)
+	b.Wallets[2].Type = "paper"
+	assert.EqualError(t, b.Verify(), `backup: wallet 2: unknown type "paper"`)
+}
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 
+	"github.com/hectorchu/gonano/keystore"
 	"github.com/hectorchu/gonano/wallet"
 	"github.com/spf13/cobra"
 )
@@ -10,10 +13,27 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new wallet or account",
+	Long: `Add a new wallet, or an account to the wallet given by -w.
+
+A new wallet is created from a seed or bip39 mnemonic, or from a random
+mnemonic, and its seed is encrypted with the key derivation function given
+by --kdf. Or it is added from a keystore file, as written by wallet export.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if walletIndex < 0 {
-			initNewWallet()
-			fmt.Println("Added wallet.")
+		if walletIndex < 0 && addKeystoreFile != "" {
+			initKeystoreWallet()
+			printResult(newWalletResult(len(wallets)-1), func() {
+				fmt.Println("Added wallet.")
+			})
+		} else if walletIndex < 0 {
+			_, mnemonic := initNewWallet()
+			r := newWalletResult(len(wallets) - 1)
+			r.Mnemonic = mnemonic
+			printResult(r, func() {
+				if mnemonic != "" {
+					fmt.Println("Your secret words are:", mnemonic)
+				}
+				fmt.Println("Added wallet.")
+			})
 		} else {
 			checkWalletIndex()
 			wi := wallets[walletIndex]
@@ -35,11 +55,35 @@ var addCmd = &cobra.Command{
 			}
 			wi.Accounts[a.Address()] = a.Index()
 			wi.save()
-			fmt.Println("Added account", a.Address())
+			index := a.Index()
+			printResult(&accountResult{Account: a.Address(), Index: &index}, func() {
+				fmt.Println("Added account", a.Address())
+				if showQR {
+					writeQR(a.Address())
+				}
+			})
 		}
 	},
 }
 
+var addKeystoreFile string
+
+// initKeystoreWallet adds a wallet from the keystore file given by
+// --keystore.
+func initKeystoreWallet() {
+	f, err := os.Open(addKeystoreFile)
+	fatalIf(err)
+	e, err := keystore.Load(f)
+	f.Close()
+	fatalIf(argError(err))
+	wi, err := newKeystoreWallet(e, sourcePassword(strconv.Itoa(len(wallets)), "Enter password: "))
+	fatalIf(err)
+	wallets = append(wallets, wi)
+	wi.initAccounts()
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().StringVar(&walletKDF, "kdf", "", "Key derivation function for a new wallet (scrypt or argon2id; default scrypt)")
+	addCmd.Flags().StringVar(&addKeystoreFile, "keystore", "", "Keystore file to add a wallet from")
 }
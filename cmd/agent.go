@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hectorchu/gonano/agent"
+	"github.com/hectorchu/gonano/signer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocketPath string
+	agentTimeout    time.Duration
+	agentAddTimeout time.Duration
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep wallets unlocked between commands",
+	Long: `Run an agent which keeps the decrypted seeds of wallets in memory, in
+the manner of ssh-agent, so that commands use them without asking for a
+password or deriving the key again. Wallets are given to the agent with
+agent add, and are forgotten when their timeout expires, when removed, or
+when the agent exits.
+
+The agent listens on a Unix socket, given by --socket, the
+GONANO_AGENT_SOCK environment variable, or agent.sock in the data
+directory. Commands use the agent found on the same socket.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := agentSocket()
+		if _, err := os.Stat(path); err == nil {
+			if conn, err := net.Dial("unix", path); err == nil {
+				conn.Close()
+				fatal(newError(codeError, "an agent is already listening on", path))
+			}
+			err = os.Remove(path)
+			fatalIf(err)
+		}
+		l, err := signer.Listen("unix:"+path, nil)
+		fatalIf(err)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		done := make(chan struct{})
+		go func() {
+			<-sig
+			close(done)
+			l.Close()
+		}()
+		printResult(map[string]string{"listen": path}, func() { fmt.Println("Listening on", path) })
+		err = http.Serve(l, &agent.Server{Timeout: agentTimeout})
+		select {
+		case <-done:
+		default:
+			fatalIf(err)
+		}
+	},
+}
+
+var agentAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Give the agent a wallet to keep unlocked",
+	Long: `Unlock a wallet, or every wallet with a seed if none is specified, and
+give its seed to the agent until --timeout expires (default the agent's
+own). Passwords are read as for any other command.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := agentClient()
+		var r []*agentResult
+		for _, i := range agentWallets() {
+			wi := wallets[i]
+			var password []byte
+			if !wi.NoPassword {
+				password = walletPassword(wi, fmt.Sprintf("Enter password for wallet %d: ", i))
+			}
+			s, err := wi.decrypt(password)
+			fatalIf(err)
+			data, err := json.Marshal(s)
+			fatalIf(err)
+			err = c.Add(wi.agentID(), data, agentAddTimeout)
+			fatalIf(err)
+			r = append(r, &agentResult{Wallet: i, ID: wi.agentID()})
+		}
+		printResult(r, func() {
+			for _, r := range r {
+				fmt.Println("Added wallet", r.Wallet)
+			}
+		})
+	},
+}
+
+var agentRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Make the agent forget a wallet",
+	Long: `Make the agent forget a wallet, or every wallet in the config if none
+is specified.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := agentClient()
+		var r []*agentResult
+		for _, i := range agentWallets() {
+			err := c.Remove(wallets[i].agentID())
+			if err == agent.ErrNotFound && walletIndex < 0 {
+				continue
+			}
+			fatalIf(err)
+			r = append(r, &agentResult{Wallet: i, ID: wallets[i].agentID()})
+		}
+		printResult(r, func() {
+			for _, r := range r {
+				fmt.Println("Removed wallet", r.Wallet)
+			}
+		})
+	},
+}
+
+var agentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the wallets held by the agent",
+	Long: `List the wallets held by the agent and when they expire. Wallets which
+are not in the config are shown by their ID.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := agentClient().List()
+		fatalIf(err)
+		ids := make(map[string]int)
+		for i, wi := range wallets {
+			if wi.Keystore != nil {
+				ids[wi.agentID()] = i
+			}
+		}
+		r := []*agentResult{}
+		for _, e := range entries {
+			i, ok := ids[e.ID]
+			if !ok {
+				i = -1
+			}
+			expires := e.Expires
+			r = append(r, &agentResult{Wallet: i, ID: e.ID, Expires: &expires})
+		}
+		printResult(r, func() {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, r := range r {
+				name := r.ID
+				if r.Wallet >= 0 {
+					name = strconv.Itoa(r.Wallet)
+				}
+				fmt.Fprintf(tw, "%s\texpires %s\n", name, r.Expires.Local().Format("2006-01-02 15:04:05"))
+			}
+			tw.Flush()
+		})
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Make the agent forget every wallet",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := agentClient().Lock()
+		fatalIf(err)
+		printResult(map[string]bool{"locked": true}, func() { fmt.Println("Locked the agent") })
+	},
+}
+
+// agentResult describes a wallet held by the agent. Wallet is -1 if it is
+// not in the config.
+type agentResult struct {
+	Wallet  int        `json:"wallet"`
+	ID      string     `json:"id"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// agentSocket returns the path of the agent's socket.
+func agentSocket() string {
+	if agentSocketPath != "" {
+		return agentSocketPath
+	}
+	if path := os.Getenv("GONANO_AGENT_SOCK"); path != "" {
+		return path
+	}
+	return filepath.Join(getDataDir(), "agent.sock")
+}
+
+func agentClient() *agent.Client {
+	return &agent.Client{Path: agentSocket()}
+}
+
+// agentWallets returns the index of the wallet given by -w, or else the
+// indices of every wallet with a seed.
+func agentWallets() (indices []int) {
+	if walletIndex >= 0 {
+		checkWalletIndex()
+		if !wallets[walletIndex].hasSeed() {
+			fatal(newError(codeInvalidArgument, "wallet has no seed"))
+		}
+		return []int{walletIndex}
+	}
+	for i, wi := range wallets {
+		if wi.hasSeed() {
+			indices = append(indices, i)
+		}
+	}
+	return
+}
+
+// hasSeed reports whether the wallet's seed is held in the config,
+// rather than by a Ledger or sign server.
+func (wi *walletInfo) hasSeed() bool {
+	return !wi.IsLedger && wi.SignerURL == ""
+}
+
+// agentID identifies the wallet's seed to the agent. It is derived from
+// the encrypted seed, so it changes along with the password.
+func (wi *walletInfo) agentID() string {
+	if wi.Keystore == nil {
+		return ""
+	}
+	h := sha256.Sum256([]byte(wi.Keystore.Ciphertext))
+	return hex.EncodeToString(h[:16])
+}
+
+// agentSecret returns the wallet's seed if it is held by the agent, or
+// nil if it is not or there is no agent.
+func (wi *walletInfo) agentSecret() *seedSecret {
+	if wi.Keystore == nil {
+		return nil
+	}
+	path := agentSocket()
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	data, err := (&agent.Client{Path: path}).Get(wi.agentID())
+	if err != nil {
+		return nil
+	}
+	s := new(seedSecret)
+	if json.Unmarshal(data, s) != nil {
+		return nil
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.PersistentFlags().StringVar(&agentSocketPath, "socket", "", "Unix socket of the agent (default $GONANO_AGENT_SOCK or agent.sock in the data directory)")
+	agentCmd.Flags().DurationVar(&agentTimeout, "timeout", 15*time.Minute, "How long wallets are kept unlocked")
+	agentCmd.AddCommand(agentAddCmd)
+	agentAddCmd.Flags().DurationVar(&agentAddTimeout, "timeout", 0, "How long the wallet is kept unlocked (default the agent's timeout)")
+	agentCmd.AddCommand(agentRemoveCmd)
+	agentCmd.AddCommand(agentListCmd)
+	agentCmd.AddCommand(agentLockCmd)
+}
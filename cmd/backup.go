@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hectorchu/gonano/backup"
+	"github.com/spf13/cobra"
+	"github.com/tyler-smith/go-bip39"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Back up every wallet to an encrypted file",
+	Long: `Back up every wallet to an encrypted, checksummed file, holding its seed
+or mnemonic, type, account indices, labels and settings, along with the
+address book. Each wallet is unlocked in turn, and the backup is encrypted
+with its own password using the key derivation function given by --kdf.
+The backup password is read from the password sources like a wallet's,
+under the keyring name backup, and may only be empty with
+--allow-empty-password. The backup does not depend on the layout of the config file, and is
+restored with import.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b := &backup.Backup{Created: time.Now().UTC()}
+		for i, wi := range wallets {
+			b.Wallets = append(b.Wallets, newBackupWallet(i, wi))
+		}
+		for _, c := range contacts {
+			b.Contacts = append(b.Contacts, &backup.Contact{Name: c.Name, Address: c.Address})
+		}
+		password := backupPassword(true)
+		f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		fatalIf(err)
+		err = backup.Write(f, b, password, kdfName())
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		if err != nil {
+			os.Remove(args[0])
+			fatal(err)
+		}
+		r := newBackupResult(args[0], b)
+		printResult(r, func() {
+			fmt.Printf("Backed up %d wallets with %d accounts to %s\n", r.Wallets, r.Accounts, r.File)
+		})
+	},
+}
+
+// backupPassword returns the backup's password from the password sources,
+// under the keyring name backup, or else asks for it. A new password is
+// confirmed by entering it twice at the terminal, and must not be empty
+// unless --allow-empty-password is given.
+func backupPassword(confirm bool) (password []byte) {
+	password = sourcePassword("backup", "Enter backup password: ")
+	if !confirm {
+		return
+	}
+	if !passwordSourced() && !bytes.Equal(password, readPassword("Re-enter backup password: ")) {
+		fatal(newError(codeInvalidArgument, "password mismatch"))
+	}
+	if len(password) == 0 && !exportAllowEmpty {
+		fatal(newError(codeInvalidArgument, "empty backup password (use --allow-empty-password to allow it)"))
+	}
+	return
+}
+
+// newBackupWallet returns the backup of a wallet, decrypting its seed.
+func newBackupWallet(i int, wi *walletInfo) (w *backup.Wallet) {
+	w = &backup.Wallet{Name: wi.Name, Representative: wi.Representative}
+	switch {
+	case wi.IsLedger:
+		w.Type = backup.Ledger
+	case wi.SignerURL != "":
+		w.Type = backup.Remote
+		w.Signer = &backup.Signer{
+			URL:    wi.SignerURL,
+			Wallet: wi.SignerWallet,
+			Cert:   wi.SignerCert,
+			Key:    wi.SignerKey,
+			CA:     wi.SignerCA,
+		}
+	default:
+		s := wi.secret(fmt.Sprintf("Enter password for wallet %d: ", i))
+		if s.Bip39 {
+			w.Type = backup.Bip39
+			mnemonic, err := bip39.NewMnemonic(s.Seed)
+			fatalIf(err)
+			w.Mnemonic, w.Passphrase = mnemonic, s.Passphrase
+		} else {
+			w.Type = backup.Seed
+			w.Seed = hex.EncodeToString(s.Seed)
+		}
+	}
+	for address, index := range wi.Accounts {
+		w.Accounts = append(w.Accounts, &backup.Account{
+			Index:   index,
+			Address: address,
+			Label:   wi.Labels[address],
+			Hidden:  wi.hidden(address),
+		})
+	}
+	sort.Slice(w.Accounts, func(i, j int) bool { return w.Accounts[i].Index < w.Accounts[j].Index })
+	return
+}
+
+var exportAllowEmpty bool
+
+var (
+	importVerify   bool
+	importNoRescan bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Restore wallets from a backup file",
+	Long: `Restore the wallets and address book from a backup file written by
+export. The seeds of the restored wallets are encrypted with a new
+password, and the wallets are rescanned for accounts unless --no-rescan
+is given. Wallets with an account already in the config, and contacts
+whose name is already taken, are skipped.
+
+With --verify, the backup is decrypted and checked, including that the
+accounts of each wallet are derived from its seed, but nothing is
+restored.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		fatalIf(err)
+		bf, err := backup.Read(f)
+		f.Close()
+		fatalIf(argError(err))
+		b, err := bf.Open(backupPassword(false))
+		fatalIf(err)
+		r := newBackupResult(args[0], b)
+		if importVerify {
+			printResult(r, func() {
+				fmt.Printf("%s is a valid backup of %d wallets with %d accounts, created %s\n",
+					r.File, r.Wallets, r.Accounts, r.Created.Local().Format("2006-01-02 15:04:05"))
+			})
+			return
+		}
+		var (
+			restore  []*backup.Wallet
+			password []byte
+		)
+		for i, w := range b.Wallets {
+			if walletRestored(w) {
+				r.Skipped = append(r.Skipped, i)
+				continue
+			}
+			if password == nil && (w.Type == backup.Seed || w.Type == backup.Bip39) {
+				password = readPassword("Enter password for restored wallets: ")
+				password2 := readPassword("Re-enter password: ")
+				if !bytes.Equal(password, password2) {
+					fatal(newError(codeInvalidArgument, "password mismatch"))
+				}
+			}
+			restore = append(restore, w)
+		}
+		for _, w := range restore {
+			wallets = append(wallets, restoreWallet(w, password))
+			r.Restored = append(r.Restored, len(wallets)-1)
+		}
+		for _, c := range b.Contacts {
+			if findContact(c.Name) == nil && checkName(c.Name, c.Address) == nil {
+				contacts = append(contacts, &contact{Name: c.Name, Address: c.Address})
+			}
+		}
+		saveContacts()
+		for _, i := range r.Restored {
+			if wi := wallets[i]; wi.w != nil && !importNoRescan {
+				err = wi.loadAccounts()
+				fatalIf(err)
+				wi.initAccounts()
+			}
+		}
+		printResult(r, func() {
+			for _, i := range r.Restored {
+				fmt.Printf("Restored wallet %d with %d accounts\n", i, len(wallets[i].Accounts))
+			}
+			for _, i := range r.Skipped {
+				fmt.Printf("Skipped wallet %d of the backup, which is already in the config\n", i)
+			}
+		})
+	},
+}
+
+// walletRestored reports whether any account of a backed up wallet is
+// already in the config.
+func walletRestored(w *backup.Wallet) bool {
+	for _, a := range w.Accounts {
+		for _, wi := range wallets {
+			if _, ok := wi.Accounts[a.Address]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// restoreWallet returns the walletInfo of a backed up wallet, encrypting
+// its seed with password.
+func restoreWallet(w *backup.Wallet, password []byte) (wi *walletInfo) {
+	wi = &walletInfo{
+		Name:           w.Name,
+		IsLedger:       w.Type == backup.Ledger,
+		Accounts:       make(map[string]uint32),
+		Labels:         make(map[string]string),
+		Representative: w.Representative,
+	}
+	if w.Signer != nil {
+		wi.SignerURL, wi.SignerWallet = w.Signer.URL, w.Signer.Wallet
+		wi.SignerCert, wi.SignerKey, wi.SignerCA = w.Signer.Cert, w.Signer.Key, w.Signer.CA
+	}
+	for _, a := range w.Accounts {
+		wi.Accounts[a.Address] = a.Index
+		if a.Label != "" {
+			wi.Labels[a.Address] = a.Label
+		}
+		if a.Hidden {
+			wi.Hidden = append(wi.Hidden, a.Address)
+		}
+	}
+	s := &seedSecret{Passphrase: w.Passphrase}
+	var err error
+	switch w.Type {
+	case backup.Seed:
+		s.Seed, err = hex.DecodeString(w.Seed)
+	case backup.Bip39:
+		s.Bip39 = true
+		s.Seed, err = bip39.EntropyFromMnemonic(w.Mnemonic)
+	default:
+		return
+	}
+	fatalIf(err)
+	err = wi.seal(s, password, kdfName())
+	fatalIf(err)
+	err = wi.initSecret(s)
+	fatalIf(err)
+	return
+}
+
+// backupResult summarises a backup file.
+type backupResult struct {
+	File     string    `json:"file"`
+	Created  time.Time `json:"created"`
+	Wallets  int       `json:"wallets"`
+	Accounts int       `json:"accounts"`
+	Contacts int       `json:"contacts"`
+	Restored []int     `json:"restored,omitempty"`
+	Skipped  []int     `json:"skipped,omitempty"`
+}
+
+func newBackupResult(file string, b *backup.Backup) (r *backupResult) {
+	r = &backupResult{File: file, Created: b.Created, Wallets: len(b.Wallets), Contacts: len(b.Contacts)}
+	for _, w := range b.Wallets {
+		r.Accounts += len(w.Accounts)
+	}
+	return
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&walletKDF, "kdf", "", "Key derivation function for the backup (scrypt or argon2id; default scrypt)")
+	exportCmd.Flags().BoolVar(&exportAllowEmpty, "allow-empty-password", false, "Allow the backup to be encrypted with an empty password")
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().BoolVar(&importVerify, "verify", false, "Check the backup without restoring it")
+	importCmd.Flags().BoolVar(&importNoRescan, "no-rescan", false, "Do not rescan the restored wallets for accounts")
+	importCmd.Flags().StringVar(&walletKDF, "kdf", "", "Key derivation function for the restored wallets (scrypt or argon2id; default scrypt)")
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+)
+
+var sendBatchFile, sendReportFile string
+
+// batchLine is a line of a batch file along with its result.
+type batchLine struct {
+	line                    int
+	id, destination, amount string
+	source, hash, errMsg    string
+	errCode                 string
+}
+
+func (l *batchLine) record() []string {
+	return []string{strconv.Itoa(l.line), l.id, l.destination, l.amount, l.source, l.hash, l.errMsg}
+}
+
+func (l *batchLine) result() (r *sendResult) {
+	r = &sendResult{Line: l.line, ID: l.id, Source: l.source, Destination: l.destination}
+	if amount, err := util.NanoAmountFromString(l.amount); err == nil {
+		r.Amount = newAmountResult(amount.Raw)
+	}
+	if l.hash != "" {
+		r.Hash, _ = hex.DecodeString(l.hash)
+	}
+	if l.errMsg != "" {
+		r.Error = &cliError{Code: l.errCode, Message: l.errMsg}
+		if r.Error.Code == "" {
+			r.Error.Code = codeError
+		}
+	}
+	return
+}
+
+// getSources returns the wallet and the accounts to send from. If no
+// account is specified, every account of the wallet is used.
+func getSources() (w *wallet.Wallet, sources []*wallet.Account) {
+	if walletAccount != "" {
+		a := getAccount()
+		return wallets[walletIndex].w, []*wallet.Account{a}
+	}
+	checkWalletIndex()
+	wi := wallets[walletIndex]
+	wi.init()
+	var indices []int
+	for _, index := range wi.Accounts {
+		indices = append(indices, int(index))
+	}
+	sort.Ints(indices)
+	for _, index := range indices {
+		index := uint32(index)
+		a, err := wi.w.NewAccount(&index)
+		fatalIf(err)
+		sources = append(sources, a)
+	}
+	return wi.w, sources
+}
+
+func readBatch(path string) (lines []*batchLine) {
+	f, err := os.Open(path)
+	fatalIf(err)
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		record, err := r.Read()
+		if err != nil {
+			fatal(fmt.Sprintf("line %d: %s", line, err))
+		}
+		if len(lines) == 0 && isBatchHeader(record) {
+			continue
+		}
+		l := &batchLine{line: line, destination: resolveAccount(record[0])}
+		if len(record) < 2 {
+			l.errMsg, l.errCode = "missing amount", codeInvalidArgument
+		} else {
+			l.amount = record[1]
+		}
+		if len(record) > 2 {
+			l.id = record[2]
+		}
+		lines = append(lines, l)
+	}
+	fatalIf(s.Err())
+	return
+}
+
+// isBatchHeader reports whether record is a header line, such as
+// "destination,amount,id".
+func isBatchHeader(record []string) bool {
+	if len(record) < 2 {
+		return false
+	}
+	switch strings.ToLower(record[0]) {
+	case "destination", "account":
+		return strings.EqualFold(record[1], "amount")
+	}
+	return false
+}
+
+// readReport reads the results of a previous run, if any.
+func readReport(path string) (done map[int]*batchLine) {
+	done = make(map[int]*batchLine)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	fatalIf(err)
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	fatalIf(err)
+	for _, record := range records {
+		if len(record) != 7 || record[5] == "" {
+			continue
+		}
+		line, err := strconv.Atoi(record[0])
+		if err != nil {
+			continue
+		}
+		done[line] = &batchLine{line, record[1], record[2], record[3], record[4], record[5], record[6], ""}
+	}
+	return
+}
+
+func sendBatch() {
+	lines := readBatch(sendBatchFile)
+	var done map[int]*batchLine
+	if sendReportFile != "" {
+		done = readReport(sendReportFile)
+	}
+	w, sources := getSources()
+	reconcileJournal(w)
+	var (
+		batch   wallet.Batch
+		pending []*batchLine
+	)
+	var report *reportWriter
+	if sendReportFile != "" || !structured() {
+		report = newReportWriter()
+		defer report.Close()
+	}
+	batch.Sources = sources
+	for i, l := range lines {
+		if d, ok := done[l.line]; ok && d.id == l.id && d.destination == l.destination && d.amount == l.amount {
+			lines[i] = d
+			continue
+		}
+		if l.errMsg == "" {
+			amount, err := util.NanoAmountFromString(l.amount)
+			if err == nil {
+				batch.Payments = append(batch.Payments, wallet.Payment{ID: l.id, Account: l.destination, Amount: amount.Raw})
+				pending = append(pending, l)
+				continue
+			}
+			l.errMsg, l.errCode = err.Error(), codeInvalidArgument
+		}
+		report.Write(l)
+	}
+	batch.Done = func(i int, r wallet.PaymentResult) {
+		l := pending[i]
+		l.source = r.Source
+		if r.Err != nil {
+			l.errMsg, l.errCode = r.Err.Error(), errorResult(r.Err).Code
+		} else {
+			l.hash = r.Hash.String()
+		}
+		report.Write(l)
+	}
+	w.Pay(&batch)
+	results := make([]*sendResult, len(lines))
+	var failed int
+	for i, l := range lines {
+		results[i] = l.result()
+		if l.hash == "" {
+			failed++
+		}
+	}
+	printResult(map[string][]*sendResult{"payments": results}, func() {})
+	if failed > 0 {
+		fatal(newError(codePartialFailure, fmt.Sprintf("%d of %d payments failed", failed, len(lines))))
+	}
+}
+
+// reportWriter writes the batch results as CSV to the report file, or to
+// stdout if there is none. Each result is flushed as soon as it is known,
+// so that a batch which fails part way can be resumed from the report.
+// Results are appended to an existing report, where a later line for a
+// payment supersedes an earlier one.
+type reportWriter struct {
+	f  *os.File
+	cw *csv.Writer
+}
+
+func newReportWriter() (w *reportWriter) {
+	w = &reportWriter{f: os.Stdout}
+	if sendReportFile != "" {
+		f, err := os.OpenFile(sendReportFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		fatalIf(err)
+		w.f = f
+	}
+	w.cw = csv.NewWriter(w.f)
+	if fi, err := w.f.Stat(); err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		w.write([]string{"line", "id", "destination", "amount", "source", "hash", "error"})
+	}
+	return
+}
+
+// Write writes the result of a line. It does nothing on a nil writer.
+func (w *reportWriter) Write(l *batchLine) {
+	if w != nil {
+		w.write(l.record())
+	}
+}
+
+func (w *reportWriter) write(record []string) {
+	w.cw.Write(record)
+	w.cw.Flush()
+	fatalIf(w.cw.Error())
+	if w.f != os.Stdout {
+		err := w.f.Sync()
+		fatalIf(err)
+	}
+}
+
+func (w *reportWriter) Close() {
+	if w.f != os.Stdout {
+		w.f.Close()
+	}
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBatchHeader(t *testing.T) {
+	const dest = "nano_1111111111111111111111111111111111111111111111111111hifc8npp"
+	path := filepath.Join(dataDir, "batch.csv")
+	read := func(text string) []*batchLine {
+		require.Nil(t, ioutil.WriteFile(path, []byte(text), 0600))
+		return readBatch(path)
+	}
+	lines := read("# payouts\nDestination, Amount, ID\n" + dest + ",1,a\n")
+	require.Len(t, lines, 1)
+	assert.Equal(t, 3, lines[0].line)
+	assert.Equal(t, dest, lines[0].destination)
+	lines = read("bob,1\n" + dest + ",2\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "bob", lines[0].destination)
+	assert.Equal(t, "1", lines[0].amount)
+	lines = read(dest + ",1\naccount,amount\n")
+	assert.Len(t, lines, 2)
+}
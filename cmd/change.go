@@ -2,25 +2,94 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/hectorchu/gonano/rpc"
 	"github.com/spf13/cobra"
 )
 
+var changeMaxWeight float64
+
 var changeCmd = &cobra.Command{
 	Use:   "change",
-	Short: "Change representative for an account",
-	Long: `Change representative for an account.
+	Short: "Change representative for an account or wallet",
+	Long: `Change representative for an account, or for every account in a
+wallet. Changing the representative of a wallet also makes it the one
+that new accounts in the wallet are opened with, once every account has
+been changed; if any fail, the wallet's default is left as it was and
+the exit status is non-zero.
+
+  change <address>
 
-  change <address>`,
+A warning is printed if the representative is offline or holds more
+than --max-weight percent of the online voting weight.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		a := getAccount()
-		hash, err := a.ChangeRep(args[0])
-		fatalIf(err)
-		fmt.Println(hash)
+		rep := resolveAccount(args[0])
+		if walletAccount == "" {
+			checkWalletIndex()
+			wi := wallets[walletIndex]
+			wi.init()
+			for _, index := range wi.Accounts {
+				_, err := wi.w.NewAccount(&index)
+				fatalIf(err)
+			}
+			warnRep(wi, rep)
+			results, err := wi.w.ChangeRep(rep)
+			fatalIf(err)
+			changes := make([]*changeResult, len(results))
+			var failed int
+			for i, r := range results {
+				changes[i] = &changeResult{Account: r.Account, Representative: rep, Hash: r.Hash, Error: errorResult(r.Err)}
+				if r.Err != nil {
+					failed++
+				}
+			}
+			// The default is only kept if every account now uses it, so
+			// that running the command again retries the failures.
+			if failed == 0 {
+				wi.Representative = rep
+				wi.save()
+			}
+			printResult(map[string][]*changeResult{"changes": changes}, func() {
+				for _, r := range results {
+					switch {
+					case r.Err != nil:
+						fmt.Println(r.Account, "failed:", r.Err)
+					case r.Hash != nil:
+						fmt.Println(r.Account, r.Hash)
+					}
+				}
+			})
+			exitIfFailed(failed, len(results))
+		} else {
+			a := getAccount()
+			warnRep(wallets[walletIndex], rep)
+			hash, err := a.ChangeRep(rep)
+			fatalIf(err)
+			r := &changeResult{Account: a.Address(), Representative: rep, Hash: hash}
+			printResult(r, func() { fmt.Println(hash) })
+		}
 	},
 }
 
+// changeResult reports the outcome of a representative change. The hash
+// is empty if the account already had the representative or is unopened.
+type changeResult struct {
+	Account        string        `json:"account"`
+	Representative string        `json:"representative"`
+	Hash           rpc.BlockHash `json:"hash,omitempty"`
+	Error          *cliError     `json:"error,omitempty"`
+}
+
+// warnRep warns if representative is unhealthy, without stopping the change.
+func warnRep(wi *walletInfo, representative string) {
+	if err := wi.w.CheckRep(representative, changeMaxWeight/100); err != nil {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(changeCmd)
+	changeCmd.Flags().Float64Var(&changeMaxWeight, "max-weight", 10, "Warn if the representative holds more than this percentage of online voting weight")
 }
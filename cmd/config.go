@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfig writes the wallets and contacts, along with any other
+// settings, to the config file. The file is replaced atomically so that
+// a crash cannot leave it truncated and lose the seeds within.
+func writeConfig() (err error) {
+	settings := viper.AllSettings()
+	w := make(map[string]*walletInfo)
+	for i, wi := range wallets {
+		w[strconv.Itoa(i)] = wi
+	}
+	settings["wallets"] = w
+	delete(settings, "contacts")
+	if len(contacts) > 0 {
+		settings["contacts"] = contacts
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err = enc.Encode(settings); err != nil {
+		return
+	}
+	if err = enc.Close(); err != nil {
+		return
+	}
+	path := viper.ConfigFileUsed()
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
+
+var confirmYes bool
+
+// confirm asks the user to confirm an action, unless --yes was given,
+// and exits if they do not.
+func confirm(format string, a ...interface{}) {
+	if confirmYes {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+" [y/N] ", a...)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(line), "y") {
+		fatal(newError(codeError, "aborted"))
+	}
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 
-	"golang.org/x/crypto/scrypt"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -19,45 +23,147 @@ func readPassword(prompt string) (password []byte) {
 	return
 }
 
-func deriveKey(password, salt []byte) (key, salt2 []byte, err error) {
-	if salt == nil {
-		salt = make([]byte, 32)
-		if _, err = rand.Read(salt); err != nil {
-			return
-		}
+var (
+	passwordFD      int
+	passwordFile    string
+	passwordKeyring bool
+	// filePassword is the password read from --password-fd or
+	// --password-file, which can only be read once.
+	filePassword []byte
+)
+
+// walletPassword returns the password of an existing wallet from the
+// first source given of --password-fd, --password-file, --password-keyring
+// and the GONANO_PASSWORD environment variable, or else asks for it.
+func walletPassword(wi *walletInfo, prompt string) []byte {
+	return sourcePassword(wi.keyringName(), prompt)
+}
+
+func sourcePassword(keyringName, prompt string) []byte {
+	var err error
+	switch {
+	case filePassword != nil:
+	case passwordFD >= 0:
+		f := os.NewFile(uintptr(passwordFD), "password-fd")
+		filePassword, err = readPasswordLine(f)
+		f.Close()
+		fatalIf(err)
+	case passwordFile != "":
+		f, err := os.Open(passwordFile)
+		fatalIf(err)
+		filePassword, err = readPasswordLine(f)
+		f.Close()
+		fatalIf(err)
+	case passwordKeyring:
+		p, err := keyringPassword(keyringName)
+		fatalIf(err)
+		return p
+	case os.Getenv("GONANO_PASSWORD") != "":
+		return []byte(os.Getenv("GONANO_PASSWORD"))
+	default:
+		return readPassword(prompt)
 	}
-	key, err = scrypt.Key(password, salt, 1048576, 8, 1, 32)
-	salt2 = salt
-	return
+	return filePassword
 }
 
-func encrypt(data, key []byte) (enc []byte, err error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
+// passwordSourced reports whether passwords are given by a source other
+// than the terminal.
+func passwordSourced() bool {
+	return filePassword != nil || passwordFD >= 0 || passwordFile != "" ||
+		passwordKeyring || os.Getenv("GONANO_PASSWORD") != ""
+}
+
+// readPasswordLine reads a password up to the first newline.
+func readPasswordLine(r io.Reader) (password []byte, err error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
 		return
 	}
-	gcm, err := cipher.NewGCM(block)
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// keyringPassword looks up a wallet's password in the OS keyring, with
+// secret-tool on Linux and the BSDs or security on macOS. It is stored
+// under the service gonano and the wallet's name, or its index if it has
+// no name.
+func keyringPassword(name string) (password []byte, err error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", "gonano", "wallet", name)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", "gonano", "-a", name, "-w")
+	default:
+		return nil, fmt.Errorf("keyring is not supported on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
 	if err != nil {
-		return
+		return nil, fmt.Errorf("keyring lookup of wallet %s: %v", name, err)
 	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = rand.Read(nonce); err != nil {
-		return
+	return bytes.TrimRight(out, "\r\n"), nil
+}
+
+// keyringStore stores a wallet's password in the OS keyring under name.
+func keyringStore(name string, password []byte) (err error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "store", "--label=gonano", "service", "gonano", "wallet", name)
+		cmd.Stdin = bytes.NewReader(password)
+	case "darwin":
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", "gonano", "-a", name, "-w", string(password))
+	default:
+		return fmt.Errorf("keyring is not supported on %s", runtime.GOOS)
+	}
+	if err = cmd.Run(); err != nil {
+		err = fmt.Errorf("keyring store of wallet %s: %v", name, err)
 	}
-	enc = gcm.Seal(nonce, nonce, data, nil)
 	return
 }
 
-func decrypt(enc, key []byte) (data []byte, err error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return
+// keyringDelete removes a wallet's password from the OS keyring, if it
+// is there.
+func keyringDelete(name string) {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		exec.Command("secret-tool", "clear", "service", "gonano", "wallet", name).Run()
+	case "darwin":
+		exec.Command("security", "delete-generic-password", "-s", "gonano", "-a", name).Run()
 	}
-	gcm, err := cipher.NewGCM(block)
+}
+
+// keyringMove moves a wallet's password in the OS keyring from one name
+// to another. If there is no password under from, any under to is removed.
+func keyringMove(from, to string) (err error) {
+	password, err := keyringPassword(from)
 	if err != nil {
+		keyringDelete(to)
+		return nil
+	}
+	if err = keyringStore(to, password); err != nil {
 		return
 	}
-	nonce := enc[:gcm.NonceSize()]
-	enc = enc[gcm.NonceSize():]
-	return gcm.Open(nil, nonce, enc, nil)
+	keyringDelete(from)
+	return
+}
+
+// keyringName returns the name under which the wallet's password is
+// stored in the OS keyring.
+func (wi *walletInfo) keyringName() string {
+	if wi.Name != "" {
+		return wi.Name
+	}
+	for i, wi2 := range wallets {
+		if wi2 == wi {
+			return strconv.Itoa(i)
+		}
+	}
+	return strconv.Itoa(len(wallets))
+}
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.IntVar(&passwordFD, "password-fd", -1, "Read the wallet password from a file descriptor")
+	f.StringVar(&passwordFile, "password-file", "", "Read the wallet password from a file")
+	f.BoolVar(&passwordKeyring, "password-keyring", false, "Look up the wallet password in the OS keyring")
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonWebsocketURL string
+	daemonPollInterval time.Duration
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Automatically receive pending amounts",
+	Long: `Automatically receive pending amounts for a wallet, or for all
+wallets if none is specified. Confirmations are watched over the node's
+websocket, and the node is polled in case any are missed. Blocks not
+accepted by the receive policy, such as amounts below the minimum, are
+left pending. Errors are logged and retried on the next poll.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var wis []*walletInfo
+		if walletIndex < 0 {
+			wis = wallets
+		} else {
+			checkWalletIndex()
+			wis = []*walletInfo{wallets[walletIndex]}
+		}
+		policy := receivePolicy()
+		ctx, cancel := context.WithCancel(context.Background())
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			<-sig
+			cancel()
+		}()
+		events := make(chan wallet.ReceiveResult)
+		var wg sync.WaitGroup
+		for _, wi := range wis {
+			wi.init()
+			for _, index := range wi.Accounts {
+				_, err := wi.w.NewAccount(&index)
+				fatalIf(err)
+			}
+			wi.w.ReceivePolicy = policy
+			r := &wallet.Receiver{
+				Wallet:       wi.w,
+				WebsocketURL: daemonWebsocketURL,
+				PollInterval: daemonPollInterval,
+				Events:       events,
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := r.Run(ctx); err != context.Canceled {
+					fatal(err)
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(events)
+		}()
+		for r := range events {
+			printReceiveResult(r)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.Flags().StringVar(&daemonWebsocketURL, "websocket", "ws://[::1]:7078", "Websocket endpoint URL (empty to only poll)")
+	daemonCmd.Flags().DurationVar(&daemonPollInterval, "poll", time.Minute, "Interval between polls of the node")
+	addReceivePolicyFlags(daemonCmd)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hectorchu/gonano/wallet"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+var dataDir string
+
+// getDataDir returns the directory holding gonano's local state,
+// creating it if necessary.
+func getDataDir() string {
+	if dataDir == "" {
+		home, err := homedir.Dir()
+		fatalIf(err)
+		dataDir = filepath.Join(home, ".gonano")
+	}
+	err := os.MkdirAll(dataDir, 0700)
+	fatalIf(err)
+	return dataDir
+}
+
+var store *wallet.FileStore
+
+func accountStore() *wallet.FileStore {
+	if store == nil {
+		var err error
+		store, err = wallet.NewFileStore(filepath.Join(getDataDir(), "state.json"))
+		fatalIf(err)
+	}
+	return store
+}
+
+var journal *wallet.FileJournal
+
+func accountJournal() *wallet.FileJournal {
+	if journal == nil {
+		var err error
+		journal, err = wallet.OpenFileJournal(filepath.Join(getDataDir(), "journal.jsonl"))
+		fatalIf(err)
+	}
+	return journal
+}
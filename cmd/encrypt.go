@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt a message to another account",
+	Long: `Encrypt a message from an account to another account.
+
+  encrypt -a <account> <recipient> <message>
+
+Only the recipient can decrypt the message, and it knows it came from the
+account. The encrypted message is printed in base64.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		a := getAccount()
+		sealed, err := a.Encrypt([]byte(args[1]), resolveAccount(args[0]))
+		fatalIf(err)
+		message := base64.StdEncoding.EncodeToString(sealed)
+		printResult(map[string]string{"message": message}, func() { fmt.Println(message) })
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt a message from another account",
+	Long: `Decrypt a message sent to an account by another account.
+
+  decrypt -a <account> <sender> <encrypted message>
+
+Fails if the message was not encrypted by the sender.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		sealed, err := base64.StdEncoding.DecodeString(args[1])
+		fatalIf(argError(err))
+		a := getAccount()
+		message, err := a.Decrypt(sealed, resolveAccount(args[0]))
+		fatalIf(err)
+		printResult(map[string]string{"message": string(message)}, func() { fmt.Println(string(message)) })
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(encryptCmd)
+	rootCmd.AddCommand(decryptCmd)
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hectorchu/gonano/history"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyFrom        string
+	historyTo          string
+	historyTypes       []string
+	historyCounterpart []string
+	historyMinAmount   string
+	historyMaxAmount   string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the transaction history of an account or wallet",
+	Long: `Show the transactions of an account, or of all accounts in a wallet
+merged chronologically. Transactions may be filtered by date, type (send,
+receive or change), counterpart and amount. Dates are given as 2006-01-02
+or in RFC 3339 format; a date without a time includes the whole day.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var accounts []string
+		if walletAccount != "" {
+			_, err := util.AddressToPubkey(walletAccount)
+			fatalIf(argError(err))
+			accounts = []string{walletAccount}
+		} else {
+			checkWalletIndex()
+			accounts = wallets[walletIndex].sortedAccounts()
+		}
+		f := historyFilter()
+		c := &rpc.Client{URL: rpcURL}
+		var histories [][]*history.Entry
+		for _, account := range accounts {
+			entries, err := history.Fetch(c, account, f.From)
+			fatalIf(err)
+			histories = append(histories, entries)
+		}
+		entries := f.Select(history.Merge(histories...))
+		r := &historyResult{Transactions: make([]*historyEntry, len(entries))}
+		for i, e := range entries {
+			r.Transactions[i] = newHistoryEntry(e)
+		}
+		printResult(r, func() { printHistory(entries, len(accounts) > 1) })
+	},
+}
+
+func historyFilter() (f *history.Filter) {
+	var err error
+	f = &history.Filter{Counterparts: resolveAccounts(historyCounterpart)}
+	if historyFrom != "" {
+		f.From, err = parseDate(historyFrom, false)
+		fatalIf(argError(err))
+	}
+	if historyTo != "" {
+		f.To, err = parseDate(historyTo, true)
+		fatalIf(argError(err))
+	}
+	for _, t := range historyTypes {
+		switch t {
+		case history.Send, history.Receive, history.Change:
+			f.Types = append(f.Types, t)
+		default:
+			fatal(newError(codeUsage, "unknown transaction type:", t))
+		}
+	}
+	for _, account := range historyCounterpart {
+		_, err := util.AddressToPubkey(account)
+		fatalIf(argError(err))
+	}
+	if historyMinAmount != "" {
+		min, err := util.NanoAmountFromString(historyMinAmount)
+		fatalIf(argError(err))
+		f.MinAmount = min.Raw
+	}
+	if historyMaxAmount != "" {
+		max, err := util.NanoAmountFromString(historyMaxAmount)
+		fatalIf(argError(err))
+		f.MaxAmount = max.Raw
+	}
+	return
+}
+
+// parseDate parses a date in 2006-01-02 or RFC 3339 format. If end is
+// set, a date without a time refers to the end of that day.
+func parseDate(s string, end bool) (t time.Time, err error) {
+	if t, err = time.Parse(time.RFC3339, s); err == nil {
+		return
+	}
+	if t, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+		return t, fmt.Errorf("invalid date %q", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return
+}
+
+// historyResult lists transactions, oldest first.
+type historyResult struct {
+	Transactions []*historyEntry `json:"transactions"`
+}
+
+type historyEntry struct {
+	Account          string        `json:"account"`
+	AccountLabel     string        `json:"account_label,omitempty"`
+	Type             string        `json:"type"`
+	Counterpart      string        `json:"counterpart"`
+	CounterpartLabel string        `json:"counterpart_label,omitempty"`
+	Amount           *amountResult `json:"amount"`
+	Balance          *amountResult `json:"balance"`
+	Time             *time.Time    `json:"time,omitempty"`
+	Height           uint64        `json:"height"`
+	Hash             rpc.BlockHash `json:"hash"`
+	Confirmed        bool          `json:"confirmed"`
+}
+
+func newHistoryEntry(e *history.Entry) (h *historyEntry) {
+	h = &historyEntry{
+		Account:          e.Account,
+		AccountLabel:     accountLabel(e.Account),
+		Type:             e.Type,
+		Counterpart:      e.Counterpart,
+		CounterpartLabel: accountLabel(e.Counterpart),
+		Amount:           newAmountResult(&e.Amount.Int),
+		Height:           e.Height,
+		Hash:             e.Hash,
+		Confirmed:        e.Confirmed,
+	}
+	if e.Balance != nil {
+		h.Balance = newAmountResult(&e.Balance.Int)
+	}
+	if !e.Time.IsZero() {
+		h.Time = &e.Time
+	}
+	return
+}
+
+func (r *historyResult) csvRecords() (records [][]string) {
+	records = append(records, []string{
+		"time", "account", "type", "counterpart", "amount_raw", "amount_nano",
+		"balance_raw", "balance_nano", "height", "hash", "confirmed",
+		"account_label", "counterpart_label",
+	})
+	for _, h := range r.Transactions {
+		var t, balanceRaw, balanceNano string
+		if h.Time != nil {
+			t = h.Time.Format(time.RFC3339)
+		}
+		if h.Balance != nil {
+			balanceRaw, balanceNano = h.Balance.Raw, h.Balance.Nano
+		}
+		records = append(records, []string{
+			t, h.Account, h.Type, h.Counterpart, h.Amount.Raw, h.Amount.Nano,
+			balanceRaw, balanceNano, fmt.Sprint(h.Height), h.Hash.String(), fmt.Sprint(h.Confirmed),
+			h.AccountLabel, h.CounterpartLabel,
+		})
+	}
+	return
+}
+
+func printHistory(entries []*history.Entry, showAccount bool) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{"TIME", "TYPE", "COUNTERPART", "AMOUNT", "HASH", "CONFIRMED"}
+	if showAccount {
+		header = append([]string{"ACCOUNT"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, e := range entries {
+		t, confirmed := "-", "no"
+		if !e.Time.IsZero() {
+			t = e.Time.Local().Format("2006-01-02 15:04:05")
+		}
+		if e.Confirmed {
+			confirmed = "yes"
+		}
+		amount := "-"
+		switch e.Type {
+		case history.Send:
+			amount = "-" + util.NanoAmount{Raw: &e.Amount.Int}.String()
+		case history.Receive:
+			amount = "+" + util.NanoAmount{Raw: &e.Amount.Int}.String()
+		}
+		row := []string{t, e.Type, labelOr(e.Counterpart), amount, e.Hash.String(), confirmed}
+		if showAccount {
+			row = append([]string{labelOr(e.Account)}, row...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// labelOr returns the label of an address if it has one, or else the
+// address.
+func labelOr(address string) string {
+	if label := accountLabel(address); label != "" {
+		return label
+	}
+	return address
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVar(&historyFrom, "from", "", "Only show transactions on or after this date")
+	historyCmd.Flags().StringVar(&historyTo, "to", "", "Only show transactions up to this date")
+	historyCmd.Flags().StringSliceVar(&historyTypes, "type", nil, "Only show transactions of these types (send, receive or change)")
+	historyCmd.Flags().StringSliceVar(&historyCounterpart, "counterpart", nil, "Only show transactions with these accounts")
+	historyCmd.Flags().StringVar(&historyMinAmount, "min", "", "Only show transactions of at least this amount of Nano")
+	historyCmd.Flags().StringVar(&historyMaxAmount, "max", "", "Only show transactions of at most this amount of Nano")
+}
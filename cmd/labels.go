@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hectorchu/gonano/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// contact is an account outside the wallets, saved in the address book.
+type contact struct {
+	Name    string
+	Address string
+}
+
+var contacts []*contact
+
+func initContacts() {
+	err := viper.UnmarshalKey("contacts", &contacts)
+	fatalIf(err)
+}
+
+func saveContacts() {
+	sort.Slice(contacts, func(i, j int) bool {
+		return strings.ToLower(contacts[i].Name) < strings.ToLower(contacts[j].Name)
+	})
+	err := writeConfig()
+	fatalIf(err)
+}
+
+// resolveAccount returns the address of an account given by its address,
+// the label of a wallet account or the name of a contact. Anything else
+// is returned unchanged, to be rejected as an invalid address.
+func resolveAccount(s string) string {
+	if _, err := util.AddressToPubkey(s); err == nil {
+		return s
+	}
+	for _, wi := range wallets {
+		for address, label := range wi.Labels {
+			if strings.EqualFold(label, s) {
+				return address
+			}
+		}
+	}
+	for _, c := range contacts {
+		if strings.EqualFold(c.Name, s) {
+			return c.Address
+		}
+	}
+	return s
+}
+
+func resolveAccounts(accounts []string) []string {
+	for i, s := range accounts {
+		accounts[i] = resolveAccount(s)
+	}
+	return accounts
+}
+
+// accountLabel returns the label or contact name of an address, if any.
+func accountLabel(address string) string {
+	for _, wi := range wallets {
+		if label, ok := wi.Labels[address]; ok {
+			return label
+		}
+	}
+	for _, c := range contacts {
+		if c.Address == address {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// checkName checks that name can be given to address, being neither an
+// address itself nor in use for another account.
+func checkName(name, address string) (err error) {
+	if name == "" || strings.ContainsAny(name, ": \t") {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	if _, err := util.AddressToPubkey(name); err == nil {
+		return errors.New("a name cannot be an address")
+	}
+	if a := resolveAccount(name); a != name && a != address {
+		return fmt.Errorf("%s is already the name of %s", name, a)
+	}
+	return
+}
+
+// normalizeAddress checks the address and returns it with the nano_
+// prefix, which is the form in which the node reports addresses.
+func normalizeAddress(address string) (string, error) {
+	pubkey, err := util.AddressToPubkey(address)
+	if err != nil {
+		return "", err
+	}
+	return util.PubkeyToAddress(pubkey)
+}
+
+var labelRemove bool
+
+var labelCmd = &cobra.Command{
+	Use:   "label <label>",
+	Short: "Label an account of a wallet",
+	Long: `Label an account of a wallet. The label may then be used in place of
+the account's address, and is shown alongside it.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if labelRemove {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		wi := findAccountWallet()
+		r := &labelResult{Account: walletAccount}
+		if labelRemove {
+			delete(wi.Labels, walletAccount)
+		} else {
+			r.Label = args[0]
+			fatalIf(argError(checkName(r.Label, walletAccount)))
+			if wi.Labels == nil {
+				wi.Labels = make(map[string]string)
+			}
+			wi.Labels[walletAccount] = r.Label
+		}
+		wi.save()
+		printResult(r, func() {
+			if r.Label == "" {
+				fmt.Println("Removed label of", r.Account)
+			} else {
+				fmt.Println("Labelled", r.Account, r.Label)
+			}
+		})
+	},
+}
+
+// labelResult is an account and its label or contact name.
+type labelResult struct {
+	Account string `json:"account"`
+	Label   string `json:"label,omitempty"`
+}
+
+var contactCmd = &cobra.Command{
+	Use:   "contact",
+	Short: "Manage the address book",
+	Long: `Manage the address book of accounts outside the wallets. The name of
+a contact may be used in place of its address.`,
+}
+
+var contactAddCmd = &cobra.Command{
+	Use:   "add <name> <address>",
+	Short: "Add a contact or change its address",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		address, err := normalizeAddress(args[1])
+		fatalIf(argError(err))
+		name := args[0]
+		if c := findContact(name); c != nil {
+			c.Address = address
+		} else {
+			fatalIf(argError(checkName(name, address)))
+			contacts = append(contacts, &contact{Name: name, Address: address})
+		}
+		saveContacts()
+		printResult(&labelResult{Account: address, Label: name}, func() {
+			fmt.Println("Added contact", name, address)
+		})
+	},
+}
+
+var contactRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a contact",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := findContact(args[0])
+		if c == nil {
+			fatal(newError(codeNotFound, "contact not found"))
+		}
+		for i := range contacts {
+			if contacts[i] == c {
+				contacts = append(contacts[:i], contacts[i+1:]...)
+				break
+			}
+		}
+		saveContacts()
+		printResult(&labelResult{Account: c.Address, Label: c.Name}, func() {
+			fmt.Println("Removed contact", c.Name)
+		})
+	},
+}
+
+var contactListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contacts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		results := []*labelResult{}
+		for _, c := range contacts {
+			results = append(results, &labelResult{Account: c.Address, Label: c.Name})
+		}
+		printResult(map[string][]*labelResult{"contacts": results}, func() {
+			for _, c := range contacts {
+				fmt.Println(c.Name, c.Address)
+			}
+		})
+	},
+}
+
+func findContact(name string) *contact {
+	for _, c := range contacts {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(labelCmd)
+	labelCmd.Flags().BoolVar(&labelRemove, "remove", false, "Remove the account's label")
+	rootCmd.AddCommand(contactCmd)
+	contactCmd.AddCommand(contactAddCmd)
+	contactCmd.AddCommand(contactRemoveCmd)
+	contactCmd.AddCommand(contactListCmd)
+}
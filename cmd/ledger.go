@@ -16,7 +16,9 @@ var ledgerCmd = &cobra.Command{
 		wi := &walletInfo{w: w, IsLedger: true}
 		wallets = append(wallets, wi)
 		wi.initAccounts()
-		fmt.Println("Added wallet.")
+		printResult(newWalletResult(len(wallets)-1), func() {
+			fmt.Println("Added wallet.")
+		})
 	},
 }
 
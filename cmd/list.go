@@ -11,54 +11,110 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// listResult lists the accounts of a wallet and their total balances.
+type listResult struct {
+	Accounts []*accountResult `json:"accounts"`
+	Balance  *amountResult    `json:"balance"`
+	Pending  *amountResult    `json:"pending"`
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List wallets or accounts within a wallet",
 	Run: func(cmd *cobra.Command, args []string) {
 		if walletAccount != "" {
 			// For when a specific account is specified. A single account is returned.
-			getBalanceAndPrint(walletAccount)
+			r, balance, pending := getBalance(walletAccount)
+			printResult(r, func() { printAccount(r, balance, pending) })
 		} else if walletIndex < 0 {
 			// For when nothing is specified, shows the number of accounts in each wallet.
-			for i, wi := range wallets {
-				n := len(wi.Accounts)
-				switch n {
-				case 1:
-					fmt.Printf("%d: %d account\n", i, n)
-				default:
-					fmt.Printf("%d: %d accounts\n", i, n)
-				}
+			results := make([]*walletResult, len(wallets))
+			for i := range wallets {
+				results[i] = newWalletResult(i)
 			}
+			printResult(map[string][]*walletResult{"wallets": results}, func() {
+				for i, wi := range wallets {
+					name := ""
+					if wi.Name != "" {
+						name = " (" + wi.Name + ")"
+					}
+					n := len(wi.Accounts)
+					switch n {
+					case 1:
+						fmt.Printf("%d%s: %d account\n", i, name, n)
+					default:
+						fmt.Printf("%d%s: %d accounts\n", i, name, n)
+					}
+				}
+			})
 		} else {
 			// For when a specific wallet is specified, shows the balance of all accounts
 			// in that wallet.
 			checkWalletIndex()
+			wi := wallets[walletIndex]
 			var accounts []string
-			for address := range wallets[walletIndex].Accounts {
-				accounts = append(accounts, address)
+			for address := range wi.Accounts {
+				if listAll || !wi.hidden(address) {
+					accounts = append(accounts, address)
+				}
 			}
 			sort.Strings(accounts)
-			var balanceSum, pendingSum big.Int
+			var (
+				r                      listResult
+				balances, pendings     []*big.Int
+				balanceSum, pendingSum big.Int
+			)
 			for _, address := range accounts {
-				balance, pending := getBalanceAndPrint(address)
-				balanceSum.Add(&balanceSum, &balance.Int)
-				pendingSum.Add(&pendingSum, &pending.Int)
-			}
-			if len(accounts) > 1 {
-				fmt.Print(strings.Repeat(" ", 61), "Sum:")
-				printAmounts(&balanceSum, &pendingSum)
+				a, balance, pending := getBalance(address)
+				r.Accounts = append(r.Accounts, a)
+				balances = append(balances, balance)
+				pendings = append(pendings, pending)
+				balanceSum.Add(&balanceSum, balance)
+				pendingSum.Add(&pendingSum, pending)
 			}
+			r.Balance = newAmountResult(&balanceSum)
+			r.Pending = newAmountResult(&pendingSum)
+			printResult(&r, func() {
+				for i, a := range r.Accounts {
+					printAccount(a, balances[i], pendings[i])
+				}
+				if len(accounts) > 1 {
+					fmt.Print(strings.Repeat(" ", 61), "Sum:")
+					printAmounts(&balanceSum, &pendingSum)
+				}
+			})
 		}
 	},
 }
 
-func getBalanceAndPrint(account string) (balance, pending *rpc.RawAmount) {
+func getBalance(account string) (r *accountResult, balance, pending *big.Int) {
 	rpcClient := rpc.Client{URL: rpcURL}
-	balance, pending, err := rpcClient.AccountBalance(account)
+	b, p, err := rpcClient.AccountBalance(account)
 	fatalIf(err)
-	fmt.Print(account)
-	printAmounts(&balance.Int, &pending.Int)
-	return balance, pending
+	r = &accountResult{
+		Account: account,
+		Label:   accountLabel(account),
+		Balance: newAmountResult(&b.Int),
+		Pending: newAmountResult(&p.Int),
+	}
+	for _, wi := range wallets {
+		if index, ok := wi.Accounts[account]; ok {
+			r.Index = &index
+			break
+		}
+	}
+	return r, &b.Int, &p.Int
+}
+
+func printAccount(r *accountResult, balance, pending *big.Int) {
+	fmt.Print(r.Account)
+	if r.Label != "" {
+		fmt.Printf(" (%s)", r.Label)
+	}
+	printAmounts(balance, pending)
+	if showQR {
+		writeQR(r.Account)
+	}
 }
 
 func printAmounts(balance, pending *big.Int) {
@@ -71,6 +127,9 @@ func printAmounts(balance, pending *big.Int) {
 	fmt.Println()
 }
 
+var listAll bool
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&listAll, "all", false, "Include hidden accounts")
 }
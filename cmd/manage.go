@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var walletCmd = &cobra.Command{
+	Use:   "wallet",
+	Short: "Remove, rename, export or change the password of a wallet",
+}
+
+var walletRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a wallet",
+	Long: `Remove a wallet from the config. Its seed is lost unless it has been
+backed up. The indices of later wallets move down by one, and their
+merchant invoice stores (merchant-N.json) and the keyring passwords of
+those without a name, which are found by index, are renamed to match.
+The removed wallet's invoice store is kept as merchant-N.removed-TIME.json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		r := newWalletResult(walletIndex)
+		confirm("Remove wallet %d with %d accounts?", walletIndex, len(r.Accounts))
+		later := append([]*walletInfo(nil), wallets[walletIndex+1:]...)
+		wallets = append(wallets[:walletIndex], wallets[walletIndex+1:]...)
+		err := writeConfig()
+		fatalIf(err)
+		err = renumberWalletData(walletIndex, later)
+		fatalIf(err)
+		printResult(r, func() { fmt.Println("Removed wallet", walletIndex) })
+	},
+}
+
+// renumberWalletData moves the data found by wallet index, the merchant
+// invoice stores and the keyring passwords of unnamed wallets, down by one
+// for the wallets which followed the removed wallet at index.
+func renumberWalletData(index int, later []*walletInfo) (err error) {
+	store := func(i int) string {
+		return filepath.Join(getDataDir(), "merchant-"+strconv.Itoa(i)+".json")
+	}
+	if _, err = os.Stat(store(index)); err == nil {
+		kept := fmt.Sprintf("merchant-%d.removed-%d.json", index, time.Now().Unix())
+		if err = os.Rename(store(index), filepath.Join(getDataDir(), kept)); err != nil {
+			return
+		}
+	}
+	// Whatever is under the removed wallet's index would otherwise be
+	// taken for the next wallet's.
+	keyringDelete(strconv.Itoa(index))
+	for i, wi := range later {
+		from, to := index+i+1, index+i
+		if _, err = os.Stat(store(from)); err == nil {
+			if err = os.Rename(store(from), store(to)); err != nil {
+				return
+			}
+		}
+		if wi.Name == "" {
+			if err = keyringMove(strconv.Itoa(from), strconv.Itoa(to)); err != nil {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+var walletRenameCmd = &cobra.Command{
+	Use:   "rename <name>",
+	Short: "Name a wallet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		wi := wallets[walletIndex]
+		confirm("Rename wallet %d to %q?", walletIndex, args[0])
+		wi.Name = args[0]
+		wi.save()
+		printResult(newWalletResult(walletIndex), func() {
+			fmt.Println("Renamed wallet", walletIndex, "to", wi.Name)
+		})
+	},
+}
+
+var walletPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the password of a wallet",
+	Long: `Change the password of a wallet. The seed is decrypted with the old
+password and encrypted with the new one under a fresh salt, using the key
+derivation function given by --kdf or else the one it used before. The
+accounts of a bip39 wallet are unchanged, as its bip39 passphrase is kept.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		wi := wallets[walletIndex]
+		if !wi.hasSeed() {
+			fatal(newError(codeInvalidArgument, "wallet has no seed to encrypt"))
+		}
+		var password []byte
+		if !wi.NoPassword {
+			password = walletPassword(wi, "Enter old password: ")
+		}
+		s, err := wi.open(password)
+		fatalIf(err)
+		password = readPassword("Enter new password: ")
+		password2 := readPassword("Re-enter new password: ")
+		if !bytes.Equal(password, password2) {
+			fatal(newError(codeInvalidArgument, "password mismatch"))
+		}
+		confirm("Change the password of wallet %d?", walletIndex)
+		kdf := kdfName()
+		if walletKDF == "" && wi.Keystore != nil {
+			kdf = wi.Keystore.KDF.Name
+		}
+		err = wi.seal(s, password, kdf)
+		fatalIf(err)
+		wi.save()
+		printResult(newWalletResult(walletIndex), func() {
+			fmt.Println("Changed the password of wallet", walletIndex)
+		})
+	},
+}
+
+var walletExportFile string
+
+var walletExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the encrypted seed of a wallet to a keystore file",
+	Long: `Export the encrypted seed of a wallet to a keystore file, which records
+the version, key derivation function and parameters, and cipher. The
+wallet is unlocked first, so that a seed in an older format is upgraded.
+The file may be added to another config with add --keystore.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		wi := wallets[walletIndex]
+		if !wi.hasSeed() {
+			fatal(newError(codeInvalidArgument, "wallet has no seed to export"))
+		}
+		wi.init()
+		f, err := os.OpenFile(walletExportFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		fatalIf(err)
+		err = wi.Keystore.Save(f)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		fatalIf(err)
+		printResult(newWalletResult(walletIndex), func() {
+			fmt.Println("Exported wallet", walletIndex, "to", walletExportFile)
+		})
+	},
+}
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Remove or hide an account of a wallet",
+}
+
+var accountRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove an account from a wallet",
+	Long: `Remove an account from a wallet. The account can be added back, as its
+key is derived from the wallet's seed, and rescan adds it back if it has
+been used.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		wi := findAccountWallet()
+		confirm("Remove account %s from wallet %d?", walletAccount, walletIndex)
+		delete(wi.Accounts, walletAccount)
+		delete(wi.Labels, walletAccount)
+		wi.setHidden(walletAccount, false)
+		wi.save()
+		printResult(&accountResult{Account: walletAccount}, func() {
+			fmt.Println("Removed account", walletAccount)
+		})
+	},
+}
+
+var accountUnhide bool
+
+var accountHideCmd = &cobra.Command{
+	Use:   "hide",
+	Short: "Hide an account of a wallet from listings",
+	Long: `Hide an account of a wallet from list, which shows it again if given
+--all. The account otherwise remains part of the wallet.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		wi := findAccountWallet()
+		if accountUnhide {
+			confirm("Unhide account %s?", walletAccount)
+		} else {
+			confirm("Hide account %s?", walletAccount)
+		}
+		wi.setHidden(walletAccount, !accountUnhide)
+		wi.save()
+		printResult(&accountResult{Account: walletAccount}, func() {
+			if accountUnhide {
+				fmt.Println("Unhid account", walletAccount)
+			} else {
+				fmt.Println("Hid account", walletAccount)
+			}
+		})
+	},
+}
+
+// findAccountWallet returns the wallet holding the account given by -a,
+// setting walletIndex if it was not given.
+func findAccountWallet() (wi *walletInfo) {
+	checkWalletAccount()
+	if walletIndex < 0 {
+		for i, wi := range wallets {
+			if _, ok := wi.Accounts[walletAccount]; ok {
+				walletIndex = i
+				break
+			}
+		}
+		if walletIndex < 0 {
+			fatal(newError(codeNotFound, "account not found in any wallet"))
+		}
+	}
+	checkWalletIndex()
+	wi = wallets[walletIndex]
+	if _, ok := wi.Accounts[walletAccount]; !ok {
+		fatal(newError(codeNotFound, "account not found in the specified wallet"))
+	}
+	return
+}
+
+// setHidden hides or unhides an account.
+func (wi *walletInfo) setHidden(address string, hidden bool) {
+	for i, a := range wi.Hidden {
+		if a == address {
+			wi.Hidden = append(wi.Hidden[:i], wi.Hidden[i+1:]...)
+			break
+		}
+	}
+	if hidden {
+		wi.Hidden = append(wi.Hidden, address)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(walletCmd)
+	walletCmd.PersistentFlags().BoolVarP(&confirmYes, "yes", "y", false, "Do not ask for confirmation")
+	walletCmd.AddCommand(walletRemoveCmd)
+	walletCmd.AddCommand(walletRenameCmd)
+	walletCmd.AddCommand(walletPasswdCmd)
+	walletPasswdCmd.Flags().StringVar(&walletKDF, "kdf", "", "Key derivation function (scrypt or argon2id; default unchanged)")
+	walletCmd.AddCommand(walletExportCmd)
+	walletExportCmd.Flags().StringVar(&walletExportFile, "file", "", "Keystore file to create")
+	walletExportCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(accountCmd)
+	accountCmd.PersistentFlags().BoolVarP(&confirmYes, "yes", "y", false, "Do not ask for confirmation")
+	accountCmd.AddCommand(accountRemoveCmd)
+	accountCmd.AddCommand(accountHideCmd)
+	accountHideCmd.Flags().BoolVar(&accountUnhide, "undo", false, "Unhide the account")
+}
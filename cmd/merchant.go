@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hectorchu/gonano/merchant"
+	"github.com/hectorchu/gonano/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	merchantListen        string
+	merchantStore         string
+	merchantFirstIndex    uint32
+	merchantTreasury      string
+	merchantWebhook       string
+	merchantWebhookSecret string
+	merchantToken         string
+	merchantWebsocketURL  string
+	merchantPollInterval  time.Duration
+)
+
+var merchantCmd = &cobra.Command{
+	Use:   "merchant",
+	Short: "Serve invoices paid to fresh accounts",
+	Long: `Serve invoices over HTTP. Each invoice is paid to its own account
+of the wallet, derived from --first-index onwards. Payments are received
+as they are confirmed, and once an invoice is paid or expires its funds
+are swept to the treasury account. Failed sweeps are logged and retried
+every minute.
+
+  POST /invoices     {"amount": "<raw>", "expiry": <seconds>, "metadata": {...}}
+  GET  /invoices/ID
+
+Status changes are posted to the webhook, signed with the HMAC-SHA256 of
+the body keyed by the webhook secret in the X-Gonano-Signature header.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		if merchantTreasury != "" {
+			merchantTreasury = resolveAccount(merchantTreasury)
+			_, err := util.AddressToPubkey(merchantTreasury)
+			fatalIf(err)
+		}
+		wi := wallets[walletIndex]
+		wi.init()
+		if merchantStore == "" {
+			merchantStore = filepath.Join(getDataDir(), "merchant-"+strconv.Itoa(walletIndex)+".json")
+		}
+		store, err := merchant.OpenStore(merchantStore, merchantFirstIndex)
+		fatalIf(err)
+		s := &merchant.Server{
+			Wallet:       wi.w,
+			Store:        store,
+			Treasury:     merchantTreasury,
+			Token:        merchantToken,
+			WebsocketURL: merchantWebsocketURL,
+			PollInterval: merchantPollInterval,
+			SweepFailed: func(inv *merchant.Invoice, err error) {
+				fmt.Fprintln(os.Stderr, "warning: failed to sweep invoice", inv.ID+":", err)
+			},
+		}
+		if merchantWebhook != "" {
+			s.Webhook = merchant.NewWebhook(merchantWebhook, merchantWebhookSecret)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			<-sig
+			cancel()
+		}()
+		go func() {
+			printResult(map[string]string{"listen": merchantListen}, func() { fmt.Println("Listening on", merchantListen) })
+			fatal(http.ListenAndServe(merchantListen, s))
+		}()
+		if err := s.Run(ctx); err != context.Canceled {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(merchantCmd)
+	merchantCmd.Flags().StringVar(&merchantListen, "listen", "[::1]:7080", "Address to serve the invoice API on")
+	merchantCmd.Flags().StringVar(&merchantStore, "store", "", "Invoice store file (default is merchant-N.json in the data directory)")
+	merchantCmd.Flags().Uint32Var(&merchantFirstIndex, "first-index", 1000000, "Account index the invoice accounts are derived from")
+	merchantCmd.Flags().StringVar(&merchantTreasury, "treasury", "", "Account to sweep invoice funds to")
+	merchantCmd.Flags().StringVar(&merchantWebhook, "webhook", "", "URL to post invoice status changes to")
+	merchantCmd.Flags().StringVar(&merchantWebhookSecret, "webhook-secret", "", "Secret the webhook signature is keyed with")
+	merchantCmd.Flags().StringVar(&merchantToken, "token", "", "Bearer token clients must present")
+	merchantCmd.Flags().StringVar(&merchantWebsocketURL, "websocket", "ws://[::1]:7078", "Websocket endpoint URL (empty to only poll)")
+	merchantCmd.Flags().DurationVar(&merchantPollInterval, "poll", time.Minute, "Interval between polls of the node")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var signMessageCmd = &cobra.Command{
+	Use:   "sign-message",
+	Short: "Sign a message to prove ownership of an account",
+	Long: `Sign a message with the key of an account, to prove ownership of it.
+
+  sign-message -a <account> <message>
+
+The signature is compatible with other Nano wallets, and is printed in hex.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		a := getAccount()
+		sig, err := a.SignMessage([]byte(args[0]))
+		fatalIf(err)
+		signature := hex.EncodeToString(sig)
+		printResult(map[string]string{"signature": signature}, func() { fmt.Println(signature) })
+	},
+}
+
+var verifyMessageCmd = &cobra.Command{
+	Use:   "verify-message",
+	Short: "Verify a message signed by an account",
+	Long: `Verify a message signed by an account.
+
+  verify-message <account> <signature> <message>
+
+Exits with an error status if the signature is invalid.`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		sig, err := hex.DecodeString(args[1])
+		fatalIf(argError(err))
+		valid, err := wallet.VerifyMessage(resolveAccount(args[0]), []byte(args[2]), sig)
+		fatalIf(argError(err))
+		if !valid {
+			fatal(newError(codeInvalidSignature, "Signature is invalid."))
+		}
+		printResult(map[string]bool{"valid": true}, func() { fmt.Println("Signature is valid.") })
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(signMessageCmd)
+	rootCmd.AddCommand(verifyMessageCmd)
+}
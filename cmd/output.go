@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormat string
+
+// Error codes reported in structured output. They are stable so that
+// scripts may rely on them.
+const (
+	codeUsage             = "usage"
+	codeNotFound          = "not_found"
+	codeInvalidArgument   = "invalid_argument"
+	codeInsufficientFunds = "insufficient_funds"
+	codeNodeError         = "node_error"
+	codePartialFailure    = "partial_failure"
+	codeInvalidSignature  = "invalid_signature"
+	codeError             = "error"
+)
+
+// cliError is an error with a code, reported by fatal.
+type cliError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *cliError) Error() string { return e.Message }
+
+func newError(code string, message ...interface{}) *cliError {
+	return &cliError{Code: code, Message: strings.TrimSuffix(fmt.Sprintln(message...), "\n")}
+}
+
+// argError marks err, if any, as caused by an invalid argument.
+func argError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &cliError{Code: codeInvalidArgument, Message: err.Error()}
+}
+
+// errorResult returns err with its code for structured output.
+func errorResult(err error) *cliError {
+	var (
+		e  *cliError
+		re rpc.Error
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return &cliError{Code: codeInsufficientFunds, Message: err.Error()}
+	case errors.As(err, &re):
+		return &cliError{Code: codeNodeError, Message: err.Error()}
+	}
+	return &cliError{Code: codeError, Message: err.Error()}
+}
+
+// exitStatus returns the exit status for an error code.
+func exitStatus(code string) int {
+	if code == codeUsage {
+		return 2
+	}
+	return 1
+}
+
+// exitIfFailed exits with an error status if any of n results failed.
+func exitIfFailed(failed, n int) {
+	if failed == 0 {
+		return
+	}
+	if structured() {
+		fatal(newError(codePartialFailure, failed, "of", n, "failed"))
+	}
+	os.Exit(1)
+}
+
+func checkOutputFormat() {
+	switch outputFormat {
+	case "text", "json", "yaml", "csv":
+	default:
+		fatal(newError(codeUsage, "unknown output format:", outputFormat))
+	}
+}
+
+// structured reports whether results are printed as JSON or YAML.
+func structured() bool {
+	return outputFormat == "json" || outputFormat == "yaml"
+}
+
+// csvResult is implemented by results which can be printed as CSV.
+type csvResult interface {
+	csvRecords() [][]string
+}
+
+// printResult prints v as JSON, YAML or CSV if selected, or else calls
+// text to print it as text. JSON results are printed one per line.
+func printResult(v interface{}, text func()) {
+	switch outputFormat {
+	case "csv":
+		r, ok := v.(csvResult)
+		if !ok {
+			fatal(newError(codeUsage, "CSV output is not supported by this command"))
+		}
+		w := csv.NewWriter(os.Stdout)
+		err := w.WriteAll(r.csvRecords())
+		fatalIf(err)
+	case "json":
+		err := json.NewEncoder(os.Stdout).Encode(v)
+		fatalIf(err)
+	case "yaml":
+		data, err := json.Marshal(v)
+		fatalIf(err)
+		var n yaml.Node
+		err = yaml.Unmarshal(data, &n)
+		fatalIf(err)
+		blockStyle(&n)
+		data, err = yaml.Marshal(&n)
+		fatalIf(err)
+		fmt.Print("---\n", string(data))
+	default:
+		text()
+	}
+}
+
+// blockStyle resets the styles of n, parsed from JSON, so that it is
+// printed in block style with strings only quoted where necessary.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// amountResult is an amount in both raw and Nano.
+type amountResult struct {
+	Raw  string `json:"raw"`
+	Nano string `json:"nano"`
+}
+
+func newAmountResult(raw *big.Int) *amountResult {
+	if raw == nil {
+		return nil
+	}
+	return &amountResult{Raw: raw.String(), Nano: util.NanoAmount{Raw: raw}.String()}
+}
+
+// walletResult describes a wallet and its accounts.
+type walletResult struct {
+	Wallet   int      `json:"wallet"`
+	Name     string   `json:"name,omitempty"`
+	Accounts []string `json:"accounts"`
+	Mnemonic string   `json:"mnemonic,omitempty"`
+}
+
+func newWalletResult(i int) *walletResult {
+	return &walletResult{Wallet: i, Name: wallets[i].Name, Accounts: wallets[i].sortedAccounts()}
+}
+
+// accountResult describes an account and, if known, its balances.
+type accountResult struct {
+	Account string        `json:"account"`
+	Label   string        `json:"label,omitempty"`
+	Index   *uint32       `json:"index,omitempty"`
+	Balance *amountResult `json:"balance,omitempty"`
+	Pending *amountResult `json:"pending,omitempty"`
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hectorchu/gonano/qr"
+	"github.com/hectorchu/gonano/uri"
+	"github.com/hectorchu/gonano/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showQR   bool
+	qrInvert bool
+	qrFile   string
+	qrScale  int
+	qrDecode string
+)
+
+var qrCmd = &cobra.Command{
+	Use:   "qr",
+	Short: "Show an account, payment request or other data as a QR code",
+	Long: `Show an account or payment request as a QR code in the terminal.
+
+  qr -a <account> [amount]
+
+Or show any text, such as a nano: URI, as a QR code.
+
+  qr <text>
+
+The code is written as a PNG or SVG image instead if --file is given a
+file ending in .png or .svg. A QR code in an image file is decoded and
+printed with --decode.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if qrDecode != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		if walletAccount != "" {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if qrDecode != "" {
+			f, err := os.Open(qrDecode)
+			fatalIf(err)
+			img, _, err := image.Decode(f)
+			f.Close()
+			fatalIf(err)
+			content, err := qr.Decode(img)
+			fatalIf(err)
+			printResult(map[string]string{"content": string(content)}, func() { fmt.Println(string(content)) })
+			return
+		}
+		content := strings.Join(args, "")
+		if walletAccount != "" {
+			_, err := util.AddressToPubkey(walletAccount)
+			fatalIf(argError(err))
+			content = walletAccount
+			if len(args) > 0 {
+				amount, err := util.NanoAmountFromString(args[0])
+				fatalIf(argError(err))
+				u := &uri.URI{Scheme: uri.Payment, Address: walletAccount, Amount: amount.Raw}
+				content = u.String()
+			}
+		}
+		writeQR(content)
+		r := map[string]string{"content": content}
+		if qrFile != "" {
+			r["file"] = qrFile
+		}
+		printResult(r, func() {})
+	},
+}
+
+// writeQR renders content to the terminal or to the --file file. Nothing
+// is written to the terminal if output is structured.
+func writeQR(content string) {
+	c, err := qr.Encode(content)
+	fatalIf(err)
+	if qrFile == "" {
+		if structured() {
+			return
+		}
+		err = c.WriteText(os.Stdout, qrInvert)
+		fatalIf(err)
+		return
+	}
+	f, err := os.Create(qrFile)
+	fatalIf(err)
+	switch strings.ToLower(filepath.Ext(qrFile)) {
+	case ".png":
+		err = c.WritePNG(f, qrScale)
+	case ".svg":
+		err = c.WriteSVG(f, qrScale)
+	default:
+		f.Close()
+		os.Remove(qrFile)
+		fatal(newError(codeUsage, "file must end in .png or .svg"))
+	}
+	fatalIf(err)
+	err = f.Close()
+	fatalIf(err)
+}
+
+func init() {
+	rootCmd.AddCommand(qrCmd)
+	qrCmd.Flags().BoolVar(&qrInvert, "invert", false, "Invert colours for terminals with a light background")
+	qrCmd.Flags().StringVarP(&qrFile, "file", "f", "", "Write a PNG or SVG image to this file")
+	qrCmd.Flags().IntVar(&qrScale, "scale", 8, "Pixels per module in images")
+	qrCmd.Flags().StringVar(&qrDecode, "decode", "", "Decode the QR code in this image file")
+	for _, cmd := range []*cobra.Command{addCmd, listCmd} {
+		cmd.Flags().BoolVar(&showQR, "qr", false, "Show accounts as QR codes")
+		cmd.Flags().BoolVar(&qrInvert, "invert", false, "Invert QR code colours for terminals with a light background")
+	}
+}
@@ -1,9 +1,22 @@
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
 	"github.com/spf13/cobra"
 )
 
+var (
+	receiveMinAmount string
+	receiveAllow     []string
+	receiveDeny      []string
+	receiveOrder     string
+	receiveMaxBlocks int
+)
+
 var receiveCmd = &cobra.Command{
 	Use:   "receive",
 	Short: "Receive all pending amounts for a wallet or account",
@@ -16,15 +29,109 @@ var receiveCmd = &cobra.Command{
 				_, err := wi.w.NewAccount(&index)
 				fatalIf(err)
 			}
-			err := wi.w.ReceivePendings()
+			wi.w.ReceivePolicy = receivePolicy()
+			results, err := wi.w.ReceivePendings()
+			printReceiveResults(results)
 			fatalIf(err)
 		} else {
-			err := getAccount().ReceivePendings()
+			a := getAccount()
+			wallets[walletIndex].w.ReceivePolicy = receivePolicy()
+			results, err := a.ReceivePendings()
+			printReceiveResults(results)
 			fatalIf(err)
 		}
 	},
 }
 
+// receiveResult reports the outcome of receiving a pending block.
+type receiveResult struct {
+	Account string        `json:"account"`
+	Link    rpc.BlockHash `json:"link"`
+	Amount  *amountResult `json:"amount"`
+	Hash    rpc.BlockHash `json:"hash,omitempty"`
+	Error   *cliError     `json:"error,omitempty"`
+}
+
+func newReceiveResult(r wallet.ReceiveResult) *receiveResult {
+	return &receiveResult{
+		Account: r.Account,
+		Link:    r.Link,
+		Amount:  newAmountResult(r.Amount),
+		Hash:    r.Hash,
+		Error:   errorResult(r.Err),
+	}
+}
+
+func printReceiveResult(r wallet.ReceiveResult) {
+	printResult(newReceiveResult(r), func() { printReceiveText(r) })
+}
+
+func printReceiveText(r wallet.ReceiveResult) {
+	if r.Err != nil && r.Link == nil {
+		fmt.Println(r.Account, "error:", r.Err)
+	} else if r.Err != nil {
+		fmt.Println(r.Account, "failed to receive", r.Link, r.Err)
+	} else {
+		fmt.Println(r.Account, "received", util.NanoAmount{Raw: r.Amount}, r.Hash)
+	}
+}
+
+// printReceiveResults prints the results, exiting with an error status
+// if any block failed to be received.
+func printReceiveResults(results []wallet.ReceiveResult) {
+	received := make([]*receiveResult, len(results))
+	var failed int
+	for i, r := range results {
+		received[i] = newReceiveResult(r)
+		if r.Err != nil {
+			failed++
+		}
+	}
+	printResult(map[string][]*receiveResult{"received": received}, func() {
+		for _, r := range results {
+			printReceiveText(r)
+		}
+	})
+	exitIfFailed(failed, len(results))
+}
+
+func addReceivePolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&receiveMinAmount, "min", "0", "Minimum amount of Nano to receive")
+	cmd.Flags().StringSliceVar(&receiveAllow, "allow", nil, "Only receive from these senders")
+	cmd.Flags().StringSliceVar(&receiveDeny, "deny", nil, "Never receive from these senders")
+	cmd.Flags().StringVar(&receiveOrder, "order", "oldest", "Order to receive in (oldest, largest or smallest first)")
+	cmd.Flags().IntVar(&receiveMaxBlocks, "max", 0, "Maximum number of blocks to receive at once (0 for no limit)")
+}
+
+func receivePolicy() (p wallet.ReceivePolicy) {
+	min, err := util.NanoAmountFromString(receiveMinAmount)
+	fatalIf(argError(err))
+	resolveAccounts(receiveAllow)
+	resolveAccounts(receiveDeny)
+	for _, account := range append(receiveAllow, receiveDeny...) {
+		_, err := util.AddressToPubkey(account)
+		fatalIf(argError(err))
+	}
+	p = wallet.ReceivePolicy{
+		MinAmount: min.Raw,
+		Allow:     receiveAllow,
+		Deny:      receiveDeny,
+		MaxBlocks: receiveMaxBlocks,
+	}
+	switch receiveOrder {
+	case "oldest":
+		p.Order = wallet.ReceiveOldestFirst
+	case "largest":
+		p.Order = wallet.ReceiveLargestFirst
+	case "smallest":
+		p.Order = wallet.ReceiveSmallestFirst
+	default:
+		fatal(newError(codeUsage, "unknown receive order:", receiveOrder))
+	}
+	return
+}
+
 func init() {
 	rootCmd.AddCommand(receiveCmd)
+	addReceivePolicyFlags(receiveCmd)
 }
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Add a wallet held by a sign server",
+	Long: `Add a wallet held by a sign server.
+
+  add remote <url> <wallet>
+
+The url is either unix:/path/to/socket or https://host:port.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		wi := &walletInfo{SignerURL: args[0], SignerWallet: args[1]}
+		wi.SignerCert, _ = cmd.Flags().GetString("tls-cert")
+		wi.SignerKey, _ = cmd.Flags().GetString("tls-key")
+		wi.SignerCA, _ = cmd.Flags().GetString("tls-ca")
+		wi.initRemote()
+		wallets = append(wallets, wi)
+		wi.initAccounts()
+		printResult(newWalletResult(len(wallets)-1), func() {
+			fmt.Println("Added wallet.")
+		})
+	},
+}
+
+func init() {
+	addCmd.AddCommand(remoteCmd)
+	remoteCmd.Flags().String("tls-cert", "", "TLS client certificate file")
+	remoteCmd.Flags().String("tls-key", "", "TLS client key file")
+	remoteCmd.Flags().String("tls-ca", "", "CA file used to verify the server")
+}
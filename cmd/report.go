@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hectorchu/gonano/history"
+	"github.com/hectorchu/gonano/report"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportFrom         string
+	reportTo           string
+	reportPeriod       string
+	reportMethod       string
+	reportPrices       string
+	reportTransactions bool
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate an accounting report for a wallet",
+	Long: `Generate a report of the opening and closing balances of a wallet and
+the Nano flowing in and out of it in each period. Transfers between the
+wallet's accounts are tagged as internal and not counted as flows.
+
+Given a CSV file of dates and the price of one Nano on that date, the
+gains realised when Nano is sent out of the wallet are computed, using
+the first in first out, last in first out or average cost method.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletIndex()
+		opts := &report.Options{
+			Accounts: wallets[walletIndex].sortedAccounts(),
+			Period:   reportPeriod,
+			Method:   reportMethod,
+		}
+		var err error
+		if reportFrom != "" {
+			opts.From, err = parseDate(reportFrom, false)
+			fatalIf(argError(err))
+		}
+		if reportTo != "" {
+			opts.To, err = parseDate(reportTo, true)
+			fatalIf(argError(err))
+		}
+		switch reportPeriod {
+		case report.Month, report.Quarter, report.Year, "":
+		default:
+			fatal(newError(codeUsage, "unknown period:", reportPeriod))
+		}
+		switch reportMethod {
+		case report.FIFO, report.LIFO, report.Average:
+		default:
+			fatal(newError(codeUsage, "unknown cost basis method:", reportMethod))
+		}
+		if reportPrices != "" {
+			f, err := os.Open(reportPrices)
+			fatalIf(err)
+			opts.Prices, err = report.LoadPrices(f)
+			f.Close()
+			fatalIf(argError(err))
+		}
+		c := &rpc.Client{URL: rpcURL}
+		var histories [][]*history.Entry
+		for _, account := range opts.Accounts {
+			entries, err := history.Fetch(c, account, time.Time{})
+			fatalIf(err)
+			histories = append(histories, entries)
+		}
+		periods, err := report.Generate(history.Merge(histories...), opts)
+		fatalIf(err)
+		r := newReportResult(periods)
+		printResult(r, func() {
+			if reportTransactions {
+				printReportTransactions(r)
+			} else {
+				printReport(r)
+			}
+		})
+	},
+}
+
+// reportResult is a report of a wallet, period by period.
+type reportResult struct {
+	Method  string          `json:"method"`
+	Periods []*periodResult `json:"periods"`
+	// transactions selects the transactions rather than the periods for
+	// CSV output.
+	transactions bool
+}
+
+type periodResult struct {
+	Start        time.Time                  `json:"start"`
+	End          time.Time                  `json:"end"`
+	Opening      *amountResult              `json:"opening"`
+	Inflow       *amountResult              `json:"inflow"`
+	Outflow      *amountResult              `json:"outflow"`
+	Internal     *amountResult              `json:"internal"`
+	Closing      *amountResult              `json:"closing"`
+	Proceeds     string                     `json:"proceeds,omitempty"`
+	Cost         string                     `json:"cost,omitempty"`
+	Gain         string                     `json:"gain,omitempty"`
+	Transactions []*reportTransactionResult `json:"transactions"`
+}
+
+type reportTransactionResult struct {
+	*historyEntry
+	Internal bool   `json:"internal"`
+	Price    string `json:"price,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Cost     string `json:"cost,omitempty"`
+	Gain     string `json:"gain,omitempty"`
+}
+
+func newReportResult(periods []*report.Period) (r *reportResult) {
+	r = &reportResult{Method: reportMethod, Periods: []*periodResult{}, transactions: reportTransactions}
+	for _, p := range periods {
+		pr := &periodResult{
+			Start:        p.Start,
+			End:          p.End,
+			Opening:      newAmountResult(p.Opening),
+			Inflow:       newAmountResult(p.Inflow),
+			Outflow:      newAmountResult(p.Outflow),
+			Internal:     newAmountResult(p.Internal),
+			Closing:      newAmountResult(p.Closing),
+			Proceeds:     fiat(p.Proceeds, 2),
+			Cost:         fiat(p.Cost, 2),
+			Gain:         fiat(p.Gain, 2),
+			Transactions: []*reportTransactionResult{},
+		}
+		for _, t := range p.Transactions {
+			pr.Transactions = append(pr.Transactions, &reportTransactionResult{
+				historyEntry: newHistoryEntry(t.Entry),
+				Internal:     t.Internal,
+				Price:        fiat(t.Price, 6),
+				Value:        fiat(t.Value, 2),
+				Cost:         fiat(t.Cost, 2),
+				Gain:         fiat(t.Gain, 2),
+			})
+		}
+		r.Periods = append(r.Periods, pr)
+	}
+	return
+}
+
+// fiat formats a value in the currency of the prices, if known.
+func fiat(x *big.Rat, prec int) string {
+	if x == nil {
+		return ""
+	}
+	return x.FloatString(prec)
+}
+
+func (r *reportResult) csvRecords() (records [][]string) {
+	if r.transactions {
+		records = append(records, []string{
+			"period", "time", "account", "type", "counterpart", "internal",
+			"amount_raw", "amount_nano", "price", "value", "cost", "gain", "hash",
+		})
+		for _, p := range r.Periods {
+			for _, t := range p.Transactions {
+				records = append(records, []string{
+					p.Start.Format(time.RFC3339), t.Time.Format(time.RFC3339), t.Account, t.Type, t.Counterpart,
+					fmt.Sprint(t.Internal), t.Amount.Raw, t.Amount.Nano, t.Price, t.Value, t.Cost, t.Gain, t.Hash.String(),
+				})
+			}
+		}
+		return
+	}
+	records = append(records, []string{
+		"start", "end", "opening_raw", "opening_nano", "inflow_raw", "inflow_nano",
+		"outflow_raw", "outflow_nano", "internal_raw", "internal_nano",
+		"closing_raw", "closing_nano", "proceeds", "cost", "gain",
+	})
+	for _, p := range r.Periods {
+		records = append(records, []string{
+			p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339),
+			p.Opening.Raw, p.Opening.Nano, p.Inflow.Raw, p.Inflow.Nano,
+			p.Outflow.Raw, p.Outflow.Nano, p.Internal.Raw, p.Internal.Nano,
+			p.Closing.Raw, p.Closing.Nano, p.Proceeds, p.Cost, p.Gain,
+		})
+	}
+	return
+}
+
+func printReport(r *reportResult) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	header := []string{"FROM", "TO", "OPENING", "INFLOW", "OUTFLOW", "INTERNAL", "CLOSING"}
+	if reportPrices != "" {
+		header = append(header, "PROCEEDS", "COST", "GAIN")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for _, p := range r.Periods {
+		row := []string{
+			p.Start.Local().Format("2006-01-02"),
+			p.End.Add(-time.Nanosecond).Local().Format("2006-01-02"),
+			p.Opening.Nano, p.Inflow.Nano, p.Outflow.Nano, p.Internal.Nano, p.Closing.Nano,
+		}
+		if reportPrices != "" {
+			row = append(row, p.Proceeds, p.Cost, p.Gain)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	tw.Flush()
+}
+
+func printReportTransactions(r *reportResult) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{"TIME", "ACCOUNT", "TYPE", "COUNTERPART", "AMOUNT"}
+	if reportPrices != "" {
+		header = append(header, "PRICE", "VALUE", "COST", "GAIN")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, p := range r.Periods {
+		for _, t := range p.Transactions {
+			typ := t.Type
+			if t.Internal {
+				typ += " (internal)"
+			}
+			row := []string{t.Time.Local().Format("2006-01-02 15:04:05"), labelOr(t.Account), typ, labelOr(t.Counterpart), t.Amount.Nano}
+			if reportPrices != "" {
+				row = append(row, t.Price, t.Value, t.Cost, t.Gain)
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	}
+	tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "Start the report on this date (default the first transaction)")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "End the report after this date (default now)")
+	reportCmd.Flags().StringVar(&reportPeriod, "period", report.Month, "Report per month, quarter or year (empty for a single period)")
+	reportCmd.Flags().StringVar(&reportMethod, "method", report.FIFO, "Cost basis method (fifo, lifo or average)")
+	reportCmd.Flags().StringVar(&reportPrices, "prices", "", "CSV file of dates and the price of one Nano, to compute gains")
+	reportCmd.Flags().BoolVar(&reportTransactions, "transactions", false, "List the transactions rather than the period totals in text and CSV output")
+}
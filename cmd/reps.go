@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hectorchu/gonano/reps"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repsRecord     string
+	repsFrom       string
+	repsMaxWeight  float64
+	repsMinVersion uint64
+	repsMaxLag     uint64
+	repsRecommend  int
+)
+
+var repsCmd = &cobra.Command{
+	Use:   "reps",
+	Short: "Rank representatives by health",
+	Long: `Rank the online representatives by health. A rep is flagged if it is
+offline, holds more than --max-weight percent of the online weight, runs
+a node older than --min-version or is more than --max-lag blocks behind.
+Healthy reps are listed first, the least concentrated first.
+
+If a wallet is given, the health of the reps its accounts delegate to is
+reported first. The data may be recorded with --record and ranked later
+with --from instead of querying the node, in which case no wallet is
+checked.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			s         *reps.Snapshot
+			delegated map[string]string
+		)
+		if walletIndex >= 0 && repsFrom == "" {
+			checkWalletIndex()
+			delegated = walletReps()
+		}
+		if repsFrom != "" {
+			f, err := os.Open(repsFrom)
+			fatalIf(err)
+			s, err = reps.Load(f)
+			f.Close()
+			fatalIf(err)
+		} else {
+			var accounts []string
+			for _, rep := range delegated {
+				accounts = append(accounts, rep)
+			}
+			var err error
+			s, err = reps.Fetch(&rpc.Client{URL: rpcURL}, accounts)
+			fatalIf(err)
+		}
+		var skipped []string
+		for account := range s.Errors {
+			skipped = append(skipped, account)
+		}
+		sort.Strings(skipped)
+		for _, account := range skipped {
+			fmt.Fprintf(os.Stderr, "warning: skipped %s: %s\n", account, s.Errors[account])
+		}
+		if repsRecord != "" {
+			f, err := os.Create(repsRecord)
+			fatalIf(err)
+			err = s.Save(f)
+			fatalIf(err)
+			err = f.Close()
+			fatalIf(err)
+		}
+		scores := s.Rank(reps.Criteria{
+			MaxShare:   repsMaxWeight / 100,
+			MinVersion: repsMinVersion,
+			MaxLag:     repsMaxLag,
+		})
+		r := newRepsResult(delegated, scores)
+		if repsRecommend > 0 {
+			r.Representatives = recommended(scores, repsRecommend)
+		}
+		printResult(r, func() {
+			if delegated != nil {
+				printDelegated(r.Delegated)
+			}
+			printScores(r.Representatives)
+		})
+	},
+}
+
+// repsResult reports the health of the wallet's reps, if a wallet is
+// given, and the ranked reps.
+type repsResult struct {
+	Delegated       []*delegatedResult `json:"delegated,omitempty"`
+	Representatives []*reps.Score      `json:"representatives"`
+}
+
+type delegatedResult struct {
+	Account        string `json:"account"`
+	Representative string `json:"representative"`
+	Status         string `json:"status"`
+}
+
+func newRepsResult(delegated map[string]string, scores []*reps.Score) (r *repsResult) {
+	r = &repsResult{Representatives: scores}
+	var accounts []string
+	for account := range delegated {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		rep := delegated[account]
+		r.Delegated = append(r.Delegated, &delegatedResult{account, rep, repStatus(rep, scores)})
+	}
+	return
+}
+
+// recommended returns up to n of the healthy reps at the head of scores.
+func recommended(scores []*reps.Score, n int) []*reps.Score {
+	for i, s := range scores {
+		if i == n || !s.Healthy() {
+			return scores[:i]
+		}
+	}
+	return scores
+}
+
+// repStatus describes the health of rep as ranked in scores.
+func repStatus(rep string, scores []*reps.Score) string {
+	for _, s := range scores {
+		if s.Account == rep {
+			if !s.Healthy() {
+				return strings.Join(s.Issues, ", ")
+			}
+			return "healthy"
+		}
+	}
+	return "not in snapshot"
+}
+
+// walletReps returns the reps of the selected wallet's accounts.
+func walletReps() (delegated map[string]string) {
+	wi := wallets[walletIndex]
+	wi.init()
+	delegated = make(map[string]string)
+	for _, index := range wi.Accounts {
+		a, err := wi.w.NewAccount(&index)
+		fatalIf(err)
+		st, err := a.State()
+		fatalIf(err)
+		if st.Representative != "" {
+			delegated[a.Address()] = st.Representative
+		}
+	}
+	return
+}
+
+func printDelegated(delegated []*delegatedResult) {
+	for _, d := range delegated {
+		fmt.Println(d.Account, "->", d.Representative+":", d.Status)
+	}
+	fmt.Println()
+}
+
+func printScores(scores []*reps.Score) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPRESENTATIVE\tWEIGHT\tDELEGATORS\tVERSION\tLAG\tSTATUS")
+	for _, s := range scores {
+		version, lag, status := "-", "-", "healthy"
+		if t := s.Telemetry; t != nil {
+			version = fmt.Sprintf("V%d.%d", t.MajorVersion, t.MinorVersion)
+			lag = fmt.Sprint(s.Lag)
+		}
+		if !s.Healthy() {
+			status = strings.Join(s.Issues, ", ")
+		}
+		fmt.Fprintf(tw, "%s\t%.2f%%\t%d\t%s\t%s\t%s\n", s.Account, s.Share*100, s.Delegators, version, lag, status)
+	}
+	tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(repsCmd)
+	repsCmd.Flags().StringVar(&repsRecord, "record", "", "Record the representative data to this file")
+	repsCmd.Flags().StringVar(&repsFrom, "from", "", "Rank representative data recorded with --record instead of querying the node")
+	repsCmd.Flags().Float64Var(&repsMaxWeight, "max-weight", 10, "Flag reps holding more than this percentage of online voting weight")
+	repsCmd.Flags().Uint64Var(&repsMinVersion, "min-version", 0, "Flag reps running an older node major version (0 for the newest seen)")
+	repsCmd.Flags().Uint64Var(&repsMaxLag, "max-lag", 1000, "Flag reps whose node is more than this many blocks behind (0 to disable)")
+	repsCmd.Flags().IntVar(&repsRecommend, "recommend", 0, "Only list this many healthy reps")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hectorchu/gonano/uri"
+	"github.com/hectorchu/gonano/util"
+	"github.com/spf13/cobra"
+)
+
+var requestLabel, requestMessage string
+
+var requestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Print a payment URI for an account",
+	Long: `Print a nano: payment URI requesting an amount of Nano to an account.
+
+  request -a <account> [amount]
+
+The URI may be given to the send command, or to other wallets.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkWalletAccount()
+		_, err := util.AddressToPubkey(walletAccount)
+		fatalIf(argError(err))
+		u := &uri.URI{
+			Scheme:  uri.Payment,
+			Address: walletAccount,
+			Label:   requestLabel,
+			Message: requestMessage,
+		}
+		if len(args) > 0 {
+			amount, err := util.NanoAmountFromString(args[0])
+			fatalIf(argError(err))
+			u.Amount = amount.Raw
+		}
+		printResult(map[string]string{"uri": u.String()}, func() { fmt.Println(u) })
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(requestCmd)
+	requestCmd.Flags().StringVar(&requestLabel, "label", "", "Label for the recipient")
+	requestCmd.Flags().StringVar(&requestMessage, "message", "", "Message for the payer")
+}
@@ -12,16 +12,26 @@ var rescanCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		checkWalletIndex()
 		wi := wallets[walletIndex]
-		n := len(wi.Accounts)
+		known := make(map[string]bool)
+		for address := range wi.Accounts {
+			known[address] = true
+		}
 		wi.init()
 		wi.initAccounts()
-		n = len(wi.Accounts) - n
-		switch n {
-		case 1:
-			fmt.Printf("Added %d account.\n", n)
-		default:
-			fmt.Printf("Added %d accounts.\n", n)
+		added := []string{}
+		for _, address := range wi.sortedAccounts() {
+			if !known[address] {
+				added = append(added, address)
+			}
 		}
+		printResult(map[string]interface{}{"wallet": walletIndex, "added": added}, func() {
+			switch n := len(added); n {
+			case 1:
+				fmt.Printf("Added %d account.\n", n)
+			default:
+				fmt.Printf("Added %d accounts.\n", n)
+			}
+		})
 	},
 }
 
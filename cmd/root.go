@@ -21,13 +21,26 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	fatalIf(err)
+	if err := rootCmd.Execute(); err != nil {
+		fatal(newError(codeUsage, err))
+	}
 }
 
+// fatal prints the error, as an error result if output is structured,
+// and exits with a non-zero status.
 func fatal(err ...interface{}) {
-	fmt.Println(err...)
-	os.Exit(1)
+	var e *cliError
+	if err2, ok := err[0].(error); ok && len(err) == 1 {
+		e = errorResult(err2)
+	} else {
+		e = newError(codeError, err...)
+	}
+	if structured() {
+		printResult(map[string]*cliError{"error": e}, nil)
+	} else {
+		fmt.Println(err...)
+	}
+	os.Exit(exitStatus(e.Code))
 }
 
 func fatalIf(err ...interface{}) {
@@ -41,15 +54,22 @@ var rpcURL, rpcWorkURL string
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gonano.yaml)")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "data directory (default is $HOME/.gonano)")
 	rootCmd.PersistentFlags().IntVarP(&walletIndex, "wallet", "w", -1, "Index of the wallet to use")
 	rootCmd.PersistentFlags().StringVarP(&walletAccount, "account", "a", "", "Account to operate on")
 	rootCmd.PersistentFlags().StringVarP(&rpcURL, "rpc", "r", "https://mynano.ninja/api/node", "RPC endpoint URL")
 	rootCmd.PersistentFlags().StringVarP(&rpcWorkURL, "rpc-work", "s", "http://[::1]:7076", "RPC endpoint URL for work generation")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml or csv)")
 	rootCmd.PersistentFlags().IntVarP(&walletAccountIndex, "account-index", "i", -1, "Index of the account within the wallet to use. Not all operations support it yet")	
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	checkOutputFormat()
+	if structured() {
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	}
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -70,7 +90,11 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err != nil {
 		err = viper.SafeWriteConfig()
 		fatalIf(err)
+		err = viper.ReadInConfig()
+		fatalIf(err)
 	}
 
 	initWallets()
+	initContacts()
+	walletAccount = resolveAccount(walletAccount)
 }
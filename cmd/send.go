@@ -2,28 +2,129 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/uri"
 	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
 	"github.com/spf13/cobra"
 )
 
+var sendID string
+
 var sendCmd = &cobra.Command{
 	Use:   "send",
 	Short: "Send an amount of Nano from an account",
 	Long: `Send an amount of Nano from an account.
 
-  send <destination> <amount>`,
-	Args: cobra.ExactArgs(2),
+  send <destination> <amount>
+
+The destination may be an address, the label of a wallet account or the
+name of a contact.
+
+The destination and amount may instead be given by a payment URI, such
+as one printed by the request command. An amount given after the URI
+must match the URI's own amount, if it has one.
+
+  send <nano:uri> [amount]
+
+Or send to many destinations listed in a CSV file of destination,amount
+lines. The payments are spread over all the accounts of the wallet if no
+account is specified. A result is appended to the report file as each
+line completes, and lines which already succeeded in the report are
+skipped, so that a failed or interrupted batch may be resumed. An
+optional third column gives a payment ID, which is never paid twice. A
+first line of destination,amount is taken as a header and skipped.
+
+  send --batch <file.csv> [--report <report.csv>]`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if sendBatchFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		if len(args) > 0 && strings.Contains(args[0], ":") {
+			return cobra.RangeArgs(1, 2)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if sendBatchFile != "" {
+			sendBatch()
+			return
+		}
 		a := getAccount()
-		amount, err := util.NanoAmountFromString(args[1])
+		w := wallets[walletIndex].w
+		reconcileJournal(w)
+		dest, amount := sendArgs(args)
+		r := &sendResult{ID: sendID, Source: a.Address(), Destination: dest, Amount: newAmountResult(amount.Raw)}
+		var err error
+		if sendID == "" {
+			r.Hash, err = a.Send(dest, amount.Raw)
+		} else {
+			p := w.Pay(&wallet.Batch{
+				Sources:  []*wallet.Account{a},
+				Payments: []wallet.Payment{{ID: sendID, Account: dest, Amount: amount.Raw}},
+			})[0]
+			r.Hash, err = p.Hash, p.Err
+		}
 		fatalIf(err)
-		hash, err := a.Send(args[0], amount.Raw)
-		fatalIf(err)
-		fmt.Println(hash)
+		printResult(r, func() { fmt.Println(r.Hash) })
 	},
 }
 
+// sendResult reports the outcome of a payment.
+type sendResult struct {
+	Line        int           `json:"line,omitempty"`
+	ID          string        `json:"id,omitempty"`
+	Source      string        `json:"source,omitempty"`
+	Destination string        `json:"destination"`
+	Amount      *amountResult `json:"amount,omitempty"`
+	Hash        rpc.BlockHash `json:"hash,omitempty"`
+	Error       *cliError     `json:"error,omitempty"`
+}
+
+// sendArgs returns the destination and amount given either directly or
+// by a payment URI. An amount given after the URI is required if the URI
+// has none, and must match it otherwise.
+func sendArgs(args []string) (dest string, amount util.NanoAmount) {
+	var err error
+	if !strings.Contains(args[0], ":") {
+		amount, err = util.NanoAmountFromString(args[1])
+		fatalIf(argError(err))
+		return resolveAccount(args[0]), amount
+	}
+	u, err := uri.Parse(args[0])
+	fatalIf(argError(err))
+	if u.Scheme != uri.Payment {
+		fatal(newError(codeInvalidArgument, "not a payment URI"))
+	}
+	if len(args) > 1 {
+		amount, err = util.NanoAmountFromString(args[1])
+		fatalIf(argError(err))
+		if u.Amount != nil && u.Amount.Cmp(amount.Raw) != 0 {
+			fatal(newError(codeInvalidArgument, "amount differs from the payment URI's amount"))
+		}
+	} else if u.Amount != nil {
+		amount.Raw = u.Amount
+	} else {
+		fatal(newError(codeUsage, "no amount given"))
+	}
+	return u.Address, amount
+}
+
+// reconcileJournal resolves payments left in doubt by a previous run.
+func reconcileJournal(w *wallet.Wallet) {
+	entries, err := w.ReconcileJournal()
+	fatalIf(err)
+	for _, e := range entries {
+		fmt.Fprintf(os.Stderr, "Recovered payment %s: %s\n", e.ID, e.State)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(sendCmd)
+	sendCmd.Flags().StringVar(&sendBatchFile, "batch", "", "CSV file of destination,amount lines to pay")
+	sendCmd.Flags().StringVar(&sendID, "id", "", "Payment ID which is never paid twice")
+	sendCmd.Flags().StringVar(&sendReportFile, "report", "", "CSV file to write batch results to (default stdout)")
 }
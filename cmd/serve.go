@@ -0,0 +1,482 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var serveListen string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the node's wallet RPC from gonano wallets",
+	Long: `Serve the node's wallet RPC actions over HTTP using gonano's
+wallets, so that tools written against the node's built-in wallet can use
+them unchanged. Wallets are named by their index. The supported actions
+are wallet_create, wallet_locked, password_enter, account_create,
+accounts_create, account_list, send, receive, wallet_balances,
+wallet_frontiers, wallet_pending and wallet_representative_set. Other
+actions are forwarded to the node given by --rpc.
+
+Wallets with a password are locked until it is given by password_enter.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		s := &rpcServer{}
+		for _, wi := range wallets {
+			if !wi.locked() {
+				wi.init()
+			} else if !wi.NoPassword || wi.unlock(nil) != nil {
+				continue
+			}
+			fatalIf(wi.loadAccounts())
+		}
+		printResult(map[string]string{"listen": serveListen}, func() { fmt.Println("Listening on", serveListen) })
+		fatalIf(http.ListenAndServe(serveListen, s))
+	},
+}
+
+// rpcServer implements the node's wallet RPC. Its mutex guards the
+// wallet list and the walletInfo of each wallet.
+type rpcServer struct {
+	mu sync.Mutex
+}
+
+type rpcRequest struct {
+	Action                 string
+	Wallet                 string
+	Account                string
+	Accounts               []string
+	Source                 string
+	Destination            string
+	Amount                 *rpc.RawAmount
+	Threshold              *rpc.RawAmount
+	ID                     string
+	Block                  rpc.BlockHash
+	Count                  string
+	Index                  string
+	Seed                   string
+	Password               string
+	Representative         string
+	UpdateExistingAccounts string `json:"update_existing_accounts"`
+}
+
+func (s *rpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	var (
+		req  rpcRequest
+		resp interface{}
+	)
+	if err = json.Unmarshal(body, &req); err == nil {
+		switch req.Action {
+		case "wallet_create":
+			resp, err = s.walletCreate(&req)
+		case "wallet_locked":
+			resp, err = s.walletLocked(&req)
+		case "password_enter":
+			resp, err = s.passwordEnter(&req)
+		case "account_create":
+			resp, err = s.accountCreate(&req)
+		case "accounts_create":
+			resp, err = s.accountsCreate(&req)
+		case "account_list":
+			resp, err = s.accountList(&req)
+		case "send":
+			resp, err = s.send(&req)
+		case "receive":
+			resp, err = s.receive(&req)
+		case "wallet_balances":
+			resp, err = s.walletBalances(&req)
+		case "wallet_frontiers":
+			resp, err = s.walletFrontiers(&req)
+		case "wallet_pending":
+			resp, err = s.walletPending(&req)
+		case "wallet_representative_set":
+			resp, err = s.walletRepresentativeSet(&req)
+		default:
+			s.forward(w, body)
+			return
+		}
+	}
+	if err != nil {
+		resp = map[string]string{"error": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// forward passes a request for any other action on to the node.
+func (s *rpcServer) forward(w http.ResponseWriter, body []byte) {
+	resp, err := http.Post(rpcURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (s *rpcServer) wallet(id string) (wi *walletInfo, err error) {
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 0 || i >= len(wallets) {
+		return nil, errors.New("Wallet not found")
+	}
+	return wallets[i], nil
+}
+
+// unlocked returns the wallet with the given ID if it is unlocked.
+func (s *rpcServer) unlocked(id string) (wi *walletInfo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wi, err = s.wallet(id); err != nil {
+		return
+	}
+	if wi.locked() {
+		return nil, errors.New("Wallet is locked")
+	}
+	return
+}
+
+// accounts returns the addresses of the wallet's accounts in index order.
+func (s *rpcServer) accounts(id string) (accounts []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wi, err := s.wallet(id)
+	if err != nil {
+		return
+	}
+	return wi.sortedAccounts(), nil
+}
+
+// account returns the account of an unlocked wallet.
+func (s *rpcServer) account(id, address string) (wi *walletInfo, a *wallet.Account, err error) {
+	if wi, err = s.unlocked(id); err != nil {
+		return
+	}
+	s.mu.Lock()
+	index, ok := wi.Accounts[address]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil, errors.New("Account not found in wallet")
+	}
+	a, err = wi.w.NewAccount(&index)
+	return
+}
+
+func parseCount(s string) (count int64, err error) {
+	if s == "" {
+		return -1, nil
+	}
+	if count, err = strconv.ParseInt(s, 10, 64); err != nil {
+		err = errors.New("Invalid count limit")
+	}
+	return
+}
+
+func (s *rpcServer) walletCreate(req *rpcRequest) (resp interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wi, _, err := newWalletInfo(req.Seed, nil)
+	if err != nil {
+		return
+	}
+	wi.Accounts = make(map[string]uint32)
+	wallets = append(wallets, wi)
+	if err = writeConfig(); err != nil {
+		wallets = wallets[:len(wallets)-1]
+		return
+	}
+	return map[string]string{"wallet": strconv.Itoa(len(wallets) - 1)}, nil
+}
+
+func (s *rpcServer) walletLocked(req *rpcRequest) (resp interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wi, err := s.wallet(req.Wallet)
+	if err != nil {
+		return
+	}
+	locked := "0"
+	if wi.locked() {
+		locked = "1"
+	}
+	return map[string]string{"locked": locked}, nil
+}
+
+func (s *rpcServer) passwordEnter(req *rpcRequest) (resp interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wi, err := s.wallet(req.Wallet)
+	if err != nil {
+		return
+	}
+	if wi.locked() {
+		if wi.unlock([]byte(req.Password)) != nil {
+			return map[string]string{"valid": "0"}, nil
+		}
+		if err = wi.loadAccounts(); err != nil {
+			return
+		}
+	}
+	return map[string]string{"valid": "1"}, nil
+}
+
+// newAccount adds the account with the given index, or the next unused
+// account if index is empty, to the wallet.
+func (s *rpcServer) newAccount(wi *walletInfo, index string) (a *wallet.Account, err error) {
+	if index != "" {
+		i, err := strconv.ParseUint(index, 10, 32)
+		if err != nil {
+			return nil, errors.New("Invalid index")
+		}
+		index := uint32(i)
+		if a, err = wi.w.NewAccount(&index); err != nil {
+			return nil, err
+		}
+	} else {
+		for {
+			if a, err = wi.w.NewAccount(nil); err != nil {
+				return
+			}
+			if _, ok := wi.Accounts[a.Address()]; !ok {
+				break
+			}
+		}
+	}
+	wi.Accounts[a.Address()] = a.Index()
+	return
+}
+
+func (s *rpcServer) accountCreate(req *rpcRequest) (resp interface{}, err error) {
+	wi, err := s.unlocked(req.Wallet)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, err := s.newAccount(wi, req.Index)
+	if err != nil {
+		return
+	}
+	if err = writeConfig(); err != nil {
+		return
+	}
+	return map[string]string{"account": a.Address()}, nil
+}
+
+func (s *rpcServer) accountsCreate(req *rpcRequest) (resp interface{}, err error) {
+	count, err := strconv.Atoi(req.Count)
+	if err != nil || count < 1 {
+		return nil, errors.New("Invalid count limit")
+	}
+	wi, err := s.unlocked(req.Wallet)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := make([]string, count)
+	for i := range accounts {
+		a, err := s.newAccount(wi, "")
+		if err != nil {
+			return nil, err
+		}
+		accounts[i] = a.Address()
+	}
+	if err = writeConfig(); err != nil {
+		return
+	}
+	return map[string][]string{"accounts": accounts}, nil
+}
+
+func (s *rpcServer) accountList(req *rpcRequest) (resp interface{}, err error) {
+	accounts, err := s.accounts(req.Wallet)
+	if err != nil {
+		return
+	}
+	if accounts == nil {
+		accounts = []string{}
+	}
+	return map[string][]string{"accounts": accounts}, nil
+}
+
+func (s *rpcServer) send(req *rpcRequest) (resp interface{}, err error) {
+	if _, err = util.AddressToPubkey(req.Destination); err != nil {
+		return nil, errors.New("Bad destination account")
+	}
+	if req.Amount == nil {
+		return nil, errors.New("Bad amount number")
+	}
+	wi, a, err := s.account(req.Wallet, req.Source)
+	if err != nil {
+		return
+	}
+	var hash rpc.BlockHash
+	if req.ID == "" {
+		hash, err = a.Send(req.Destination, &req.Amount.Int)
+	} else {
+		r := wi.w.Pay(&wallet.Batch{
+			Sources:  []*wallet.Account{a},
+			Payments: []wallet.Payment{{ID: req.ID, Account: req.Destination, Amount: &req.Amount.Int}},
+		})[0]
+		hash, err = r.Hash, r.Err
+	}
+	if err != nil {
+		return
+	}
+	return map[string]rpc.BlockHash{"block": hash}, nil
+}
+
+func (s *rpcServer) receive(req *rpcRequest) (resp interface{}, err error) {
+	_, a, err := s.account(req.Wallet, req.Account)
+	if err != nil {
+		return
+	}
+	hash, err := a.ReceivePending(req.Block)
+	if err != nil {
+		return
+	}
+	return map[string]rpc.BlockHash{"block": hash}, nil
+}
+
+func (s *rpcServer) walletBalances(req *rpcRequest) (resp interface{}, err error) {
+	accounts, err := s.accounts(req.Wallet)
+	if err != nil {
+		return
+	}
+	balances := make(map[string]map[string]*rpc.RawAmount)
+	if len(accounts) > 0 {
+		client := rpc.Client{URL: rpcURL}
+		b, err := client.AccountsBalances(accounts)
+		if err != nil {
+			return nil, err
+		}
+		for account, b := range b {
+			if req.Threshold != nil && b.Balance.Cmp(&req.Threshold.Int) < 0 {
+				continue
+			}
+			balances[account] = map[string]*rpc.RawAmount{
+				"balance":    b.Balance,
+				"pending":    b.Pending,
+				"receivable": b.Pending,
+			}
+		}
+	}
+	return map[string]interface{}{"balances": balances}, nil
+}
+
+func (s *rpcServer) walletFrontiers(req *rpcRequest) (resp interface{}, err error) {
+	accounts, err := s.accounts(req.Wallet)
+	if err != nil {
+		return
+	}
+	frontiers := make(map[string]rpc.BlockHash)
+	if len(accounts) > 0 {
+		client := rpc.Client{URL: rpcURL}
+		if frontiers, err = client.AccountsFrontiers(accounts); err != nil {
+			return
+		}
+	}
+	return map[string]interface{}{"frontiers": frontiers}, nil
+}
+
+func (s *rpcServer) walletPending(req *rpcRequest) (resp interface{}, err error) {
+	count, err := parseCount(req.Count)
+	if err != nil {
+		return
+	}
+	accounts, err := s.accounts(req.Wallet)
+	if err != nil {
+		return
+	}
+	var pending map[string]rpc.HashToPendingMap
+	if len(accounts) > 0 {
+		client := rpc.Client{URL: rpcURL}
+		if pending, err = client.AccountsPending(accounts, count); err != nil {
+			return
+		}
+	}
+	// The shape of the result follows the node: a list of hashes, or
+	// amounts by hash if a threshold is given, or amounts and sources
+	// by hash if the source is requested.
+	blocks := make(map[string]interface{})
+	for account, pending := range pending {
+		var (
+			hashes  []string
+			amounts = make(map[string]*rpc.RawAmount)
+			sources = make(map[string]interface{})
+		)
+		for hash, p := range pending {
+			if req.Threshold != nil && p.Amount.Cmp(&req.Threshold.Int) < 0 {
+				continue
+			}
+			hashes = append(hashes, hash)
+			amounts[hash] = p.Amount
+			sources[hash] = map[string]interface{}{"amount": p.Amount, "source": p.Source}
+		}
+		switch {
+		case len(hashes) == 0:
+		case req.Source == "true":
+			blocks[account] = sources
+		case req.Threshold != nil:
+			blocks[account] = amounts
+		default:
+			sort.Strings(hashes)
+			blocks[account] = hashes
+		}
+	}
+	return map[string]interface{}{"blocks": blocks}, nil
+}
+
+func (s *rpcServer) walletRepresentativeSet(req *rpcRequest) (resp interface{}, err error) {
+	if _, err = util.AddressToPubkey(req.Representative); err != nil {
+		return nil, errors.New("Bad account number")
+	}
+	wi, err := s.unlocked(req.Wallet)
+	if err != nil {
+		return
+	}
+	if req.UpdateExistingAccounts == "true" {
+		results, err := wi.w.ChangeRep(req.Representative)
+		if err != nil {
+			return nil, err
+		}
+		// The default is left unchanged unless every account changed.
+		for _, r := range results {
+			if r.Err != nil {
+				return nil, fmt.Errorf("%s: %v", r.Account, r.Err)
+			}
+		}
+	} else {
+		wi.w.SetRepresentative(req.Representative)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wi.Representative = req.Representative
+	if err = writeConfig(); err != nil {
+		return
+	}
+	return map[string]string{"set": "1"}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveListen, "listen", "[::1]:7077", "Address to serve the wallet RPC on")
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hectorchu/gonano/keystore"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSeed = "0000000000000000000000000000000000000000000000000000000000000001"
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "gonano")
+	if err != nil {
+		panic(err)
+	}
+	dataDir = dir
+	walletKDF = keystore.Argon2id
+	viper.SetConfigFile(filepath.Join(dir, "config.yaml"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeUpstream is a node answering the RPCs the wallet actions query,
+// and recording the actions it is sent.
+type fakeUpstream struct {
+	*httptest.Server
+	actions []string
+}
+
+func newFakeUpstream(t *testing.T) *fakeUpstream {
+	u := &fakeUpstream{}
+	u.Server = httptest.NewServer(u)
+	t.Cleanup(u.Close)
+	rpcURL = u.URL
+	return u
+}
+
+func (u *fakeUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action   string
+		Accounts []string
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	u.actions = append(u.actions, req.Action)
+	var resp interface{}
+	switch req.Action {
+	case "accounts_pending":
+		blocks := make(map[string]interface{})
+		for _, account := range req.Accounts {
+			blocks[account] = map[string]interface{}{
+				"01": map[string]string{"amount": "10", "source": "nano_src1"},
+				"02": map[string]string{"amount": "1000", "source": "nano_src2"},
+			}
+		}
+		resp = map[string]interface{}{"blocks": blocks}
+	case "accounts_balances":
+		balances := make(map[string]interface{})
+		for i, account := range req.Accounts {
+			balance := "5"
+			if i == 0 {
+				balance = "500"
+			}
+			balances[account] = map[string]string{"balance": balance, "pending": "1"}
+		}
+		resp = map[string]interface{}{"balances": balances}
+	case "block_count":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(`{"count":"42"}`))
+		return
+	default:
+		resp = map[string]string{"error": "Unknown command"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// newTestWallets replaces the wallets with one holding count accounts,
+// locked if password is not empty.
+func newTestWallets(t *testing.T, password string, count int) (accounts []string) {
+	wi, _, err := newWalletInfo(testSeed, []byte(password))
+	require.Nil(t, err)
+	wi.Accounts = make(map[string]uint32)
+	for i := 0; i < count; i++ {
+		a, err := wi.w.NewAccount(nil)
+		require.Nil(t, err)
+		wi.Accounts[a.Address()] = a.Index()
+		accounts = append(accounts, a.Address())
+	}
+	if password != "" {
+		wi.w = nil
+	}
+	wallets = []*walletInfo{wi}
+	return
+}
+
+func serveRequest(t *testing.T, s *rpcServer, body string) (resp map[string]interface{}) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return
+}
+
+func TestServeWalletPending(t *testing.T) {
+	newFakeUpstream(t)
+	accounts := newTestWallets(t, "", 1)
+	s := &rpcServer{}
+	resp := serveRequest(t, s, `{"action":"wallet_pending","wallet":"0"}`)
+	assert.Equal(t, map[string]interface{}{
+		"blocks": map[string]interface{}{accounts[0]: []interface{}{"01", "02"}},
+	}, resp)
+	resp = serveRequest(t, s, `{"action":"wallet_pending","wallet":"0","threshold":"100"}`)
+	assert.Equal(t, map[string]interface{}{
+		"blocks": map[string]interface{}{accounts[0]: map[string]interface{}{"02": "1000"}},
+	}, resp)
+	resp = serveRequest(t, s, `{"action":"wallet_pending","wallet":"0","source":"true"}`)
+	assert.Equal(t, map[string]interface{}{
+		"blocks": map[string]interface{}{accounts[0]: map[string]interface{}{
+			"01": map[string]interface{}{"amount": "10", "source": "nano_src1"},
+			"02": map[string]interface{}{"amount": "1000", "source": "nano_src2"},
+		}},
+	}, resp)
+	resp = serveRequest(t, s, `{"action":"wallet_pending","wallet":"0","threshold":"10000"}`)
+	assert.Equal(t, map[string]interface{}{"blocks": map[string]interface{}{}}, resp)
+	resp = serveRequest(t, s, `{"action":"wallet_pending","wallet":"1"}`)
+	assert.Equal(t, "Wallet not found", resp["error"])
+}
+
+func TestServeWalletBalances(t *testing.T) {
+	newFakeUpstream(t)
+	accounts := newTestWallets(t, "", 2)
+	s := &rpcServer{}
+	resp := serveRequest(t, s, `{"action":"wallet_balances","wallet":"0"}`)
+	assert.Len(t, resp["balances"], 2)
+	resp = serveRequest(t, s, `{"action":"wallet_balances","wallet":"0","threshold":"100"}`)
+	assert.Equal(t, map[string]interface{}{
+		"balances": map[string]interface{}{accounts[0]: map[string]interface{}{
+			"balance":    "500",
+			"pending":    "1",
+			"receivable": "1",
+		}},
+	}, resp)
+}
+
+func TestServeForward(t *testing.T) {
+	u := newFakeUpstream(t)
+	newTestWallets(t, "", 0)
+	rec := httptest.NewRecorder()
+	(&rpcServer{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"action":"block_count"}`)))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"count":"42"}`, rec.Body.String())
+	assert.Equal(t, []string{"block_count"}, u.actions)
+}
+
+func TestServeLocking(t *testing.T) {
+	newFakeUpstream(t)
+	accounts := newTestWallets(t, "secret", 1)
+	s := &rpcServer{}
+	resp := serveRequest(t, s, `{"action":"wallet_locked","wallet":"0"}`)
+	assert.Equal(t, "1", resp["locked"])
+	resp = serveRequest(t, s, `{"action":"account_create","wallet":"0"}`)
+	assert.Equal(t, "Wallet is locked", resp["error"])
+	resp = serveRequest(t, s, `{"action":"password_enter","wallet":"0","password":"wrong"}`)
+	assert.Equal(t, "0", resp["valid"])
+	resp = serveRequest(t, s, `{"action":"wallet_locked","wallet":"0"}`)
+	assert.Equal(t, "1", resp["locked"])
+	resp = serveRequest(t, s, `{"action":"password_enter","wallet":"0","password":"secret"}`)
+	assert.Equal(t, "1", resp["valid"])
+	resp = serveRequest(t, s, `{"action":"wallet_locked","wallet":"0"}`)
+	assert.Equal(t, "0", resp["locked"])
+	resp = serveRequest(t, s, `{"action":"account_create","wallet":"0"}`)
+	require.Nil(t, resp["error"])
+	account := resp["account"]
+	assert.NotEqual(t, accounts[0], account)
+	resp = serveRequest(t, s, `{"action":"account_list","wallet":"0"}`)
+	assert.Equal(t, []interface{}{accounts[0], account}, resp["accounts"])
+	config, err := ioutil.ReadFile(viper.ConfigFileUsed())
+	require.Nil(t, err)
+	assert.Contains(t, string(config), account)
+}
+
+func TestServeSaveError(t *testing.T) {
+	newFakeUpstream(t)
+	newTestWallets(t, "", 0)
+	path := viper.ConfigFileUsed()
+	viper.SetConfigFile(filepath.Join(path, "missing", "config.yaml"))
+	defer viper.SetConfigFile(path)
+	s := &rpcServer{}
+	resp := serveRequest(t, s, `{"action":"wallet_create","seed":"`+testSeed+`"}`)
+	assert.NotNil(t, resp["error"])
+	assert.Len(t, wallets, 1)
+	resp = serveRequest(t, s, `{"action":"account_create","wallet":"0"}`)
+	assert.NotNil(t, resp["error"])
+}
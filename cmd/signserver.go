@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/signer"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signServerListen     string
+	signServerWallets    []int
+	signServerAuditLog   string
+	signServerAllowDests []string
+	signServerAllowReps  []string
+	signServerDailyLimit string
+	signServerCert       string
+	signServerKey        string
+	signServerClientCA   string
+)
+
+var signServerCmd = &cobra.Command{
+	Use:   "sign-server",
+	Short: "Serve sign requests for wallets",
+	Long: `Serve sign requests for wallets over a Unix socket or mutually
+authenticated TLS. Wallets are named by their index. A Unix socket is
+only accessible to its owner, and its directory must not be writable by
+others.
+
+  sign-server --listen unix:/path/to/socket --wallets 0,1
+  sign-server --listen :7090 --tls-cert cert.pem --tls-key key.pem --client-ca ca.pem`,
+	Run: func(cmd *cobra.Command, args []string) {
+		s := &signer.Server{
+			Signers: make(map[string]wallet.Signer),
+			Policy: signer.Policy{
+				AllowedDestinations: signServerAllowDests,
+				Representatives:     signServerAllowReps,
+			},
+			RPC: rpc.Client{URL: rpcURL},
+		}
+		for _, account := range append(signServerAllowDests, signServerAllowReps...) {
+			_, err := util.AddressToPubkey(account)
+			fatalIf(err)
+		}
+		if signServerDailyLimit != "" {
+			limit, err := util.NanoAmountFromString(signServerDailyLimit)
+			fatalIf(err)
+			s.Policy.DailyLimit = limit.Raw
+		}
+		if len(signServerWallets) == 0 {
+			for i := range wallets {
+				signServerWallets = append(signServerWallets, i)
+			}
+		}
+		for _, i := range signServerWallets {
+			walletIndex = i
+			checkWalletIndex()
+			wi := wallets[i]
+			wi.init()
+			s.Signers[strconv.Itoa(i)] = wi.w.Signer()
+		}
+		audit, err := os.OpenFile(signServerAuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		fatalIf(err)
+		defer audit.Close()
+		s.Audit = audit
+		var tlsConfig *tls.Config
+		if !strings.HasPrefix(signServerListen, "unix:") {
+			tlsConfig, err = signer.LoadTLSConfig(signServerCert, signServerKey, signServerClientCA, true)
+			fatalIf(err)
+		}
+		l, err := signer.Listen(signServerListen, tlsConfig)
+		fatalIf(err)
+		printResult(map[string]string{"listen": signServerListen}, func() { fmt.Println("Listening on", signServerListen) })
+		fatalIf(http.Serve(l, s))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(signServerCmd)
+	f := signServerCmd.Flags()
+	f.StringVar(&signServerListen, "listen", "unix:gonano-signer.sock", "Unix socket (unix:path) or TCP address to listen on")
+	f.IntSliceVar(&signServerWallets, "wallets", nil, "Indices of the wallets to serve (default all)")
+	f.StringVar(&signServerAuditLog, "audit-log", "gonano-audit.log", "Path of the audit log")
+	f.StringSliceVar(&signServerAllowDests, "allow-dest", nil, "Accounts that may be sent to (default any)")
+	f.StringSliceVar(&signServerAllowReps, "allow-rep", nil, "Accounts that may be set as representative (default any)")
+	f.StringVar(&signServerDailyLimit, "daily-limit", "", "Maximum amount of Nano each account may send per day")
+	f.StringVar(&signServerCert, "tls-cert", "", "TLS certificate file")
+	f.StringVar(&signServerKey, "tls-key", "", "TLS key file")
+	f.StringVar(&signServerClientCA, "client-ca", "", "CA file used to verify client certificates")
+}
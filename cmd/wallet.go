@@ -3,21 +3,53 @@ package cmd
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/hectorchu/gonano/keystore"
+	"github.com/hectorchu/gonano/signer"
 	"github.com/hectorchu/gonano/wallet"
 	"github.com/spf13/viper"
 	"github.com/tyler-smith/go-bip39"
 )
 
 type walletInfo struct {
-	w                 *wallet.Wallet
-	Seed, Salt        string
+	w    *wallet.Wallet
+	Name string `yaml:",omitempty"`
+	// Keystore is the encrypted seed. Seed and Salt hold it instead in
+	// configs written before keystore envelopes, along with Passphrase,
+	// the encrypted bip39 passphrase if it was not the password.
+	Keystore          *keystore.Envelope `yaml:",omitempty"`
+	Seed, Salt        string             `yaml:",omitempty"`
+	Passphrase        string             `yaml:",omitempty"`
 	IsBip39, IsLedger bool
-	Accounts          map[string]uint32
+	// NoPassword is set if the seed is encrypted with an empty password.
+	NoPassword     bool `yaml:",omitempty"`
+	Accounts       map[string]uint32
+	Labels         map[string]string `yaml:",omitempty"`
+	Hidden         []string          `yaml:",omitempty"`
+	SignerURL      string
+	SignerWallet   string
+	SignerCert     string
+	SignerKey      string
+	SignerCA       string
+	Representative string
 }
 
 var wallets []*walletInfo
+var walletKDF string
+
+// kdfName returns the key derivation function given by --kdf, or scrypt.
+func kdfName() string {
+	if walletKDF == "" {
+		return keystore.Scrypt
+	}
+	return walletKDF
+}
+
 var walletIndex int
 var walletAccount string
 var walletAccountIndex int
@@ -30,11 +62,26 @@ func initWallets() {
 			return fmt.Sprintf("wallets.%d.%s", i, s)
 		}
 		wallets[i] = &walletInfo{
-			Seed:     viper.GetString(key("seed")),
-			Salt:     viper.GetString(key("salt")),
-			IsBip39:  viper.GetBool(key("isbip39")),
-			IsLedger: viper.GetBool(key("isledger")),
-			Accounts: make(map[string]uint32),
+			Name:           viper.GetString(key("name")),
+			Seed:           viper.GetString(key("seed")),
+			Salt:           viper.GetString(key("salt")),
+			IsBip39:        viper.GetBool(key("isbip39")),
+			IsLedger:       viper.GetBool(key("isledger")),
+			NoPassword:     viper.GetBool(key("nopassword")),
+			Passphrase:     viper.GetString(key("passphrase")),
+			Accounts:       make(map[string]uint32),
+			Labels:         viper.GetStringMapString(key("labels")),
+			Hidden:         viper.GetStringSlice(key("hidden")),
+			SignerURL:      viper.GetString(key("signerurl")),
+			SignerWallet:   viper.GetString(key("signerwallet")),
+			SignerCert:     viper.GetString(key("signercert")),
+			SignerKey:      viper.GetString(key("signerkey")),
+			SignerCA:       viper.GetString(key("signerca")),
+			Representative: viper.GetString(key("representative")),
+		}
+		if viper.IsSet(key("keystore")) {
+			err := viper.UnmarshalKey(key("keystore"), &wallets[i].Keystore)
+			fatalIf(err)
 		}
 		for k, v := range viper.GetStringMap(key("accounts")) {
 			wallets[i].Accounts[k] = uint32(v.(int))
@@ -44,57 +91,69 @@ func initWallets() {
 
 func checkWalletIndex() {
 	if walletIndex < 0 {
-		fatal("wallet index (-w) not specified")
+		fatal(newError(codeUsage, "wallet index (-w) not specified"))
 	} else if walletIndex >= len(wallets) {
-		fatal("wallet index out of range")
+		fatal(newError(codeNotFound, "wallet index out of range"))
 	}
 }
 
 func checkWalletAccount() {
 	if walletAccount == "" {
-		fatal("wallet account (-a) not specified")
+		fatal(newError(codeUsage, "wallet account (-a) not specified"))
 	}
 }
 
-func initNewWallet() (wi *walletInfo) {
+func initNewWallet() (wi *walletInfo, mnemonic string) {
 	seed := string(readPassword("Enter seed or bip39 mnemonic (leave blank for random): "))
 	password := readPassword("Enter password: ")
 	password2 := readPassword("Re-enter password: ")
 	if !bytes.Equal(password, password2) {
-		fatal("password mismatch")
-	}
-	key, salt, err := deriveKey(password, nil)
-	fatalIf(err)
-	wi = &walletInfo{Salt: hex.EncodeToString(salt)}
-	initBip39 := func(entropy []byte) {
-		enc, err := encrypt(entropy, key)
-		fatalIf(err)
-		wi.Seed = hex.EncodeToString(enc)
-		wi.IsBip39 = true
-		wi.initBip39(entropy, password)
+		fatal(newError(codeInvalidArgument, "password mismatch"))
 	}
+	wi, mnemonic, err := newWalletInfo(seed, password)
+	fatalIf(argError(err))
+	wallets = append(wallets, wi)
+	wi.initAccounts()
+	return
+}
+
+// newWalletInfo creates a wallet from a hex seed or bip39 mnemonic, or
+// from a random mnemonic which is returned if seed is empty.
+func newWalletInfo(seed string, password []byte) (wi *walletInfo, mnemonic string, err error) {
+	s := &seedSecret{Bip39: true, Passphrase: string(password)}
 	if seed == "" {
-		entropy, err := bip39.NewEntropy(256)
-		fatalIf(err)
-		mnemonic, err := bip39.NewMnemonic(entropy)
-		fatalIf(err)
-		fmt.Println("Your secret words are:", mnemonic)
-		initBip39(entropy)
-	} else {
-		seed2, err := hex.DecodeString(seed)
-		if err == nil {
-			enc, err := encrypt(seed2, key)
-			fatalIf(err)
-			wi.Seed = hex.EncodeToString(enc)
-			wi.initRegularSeed(seed2)
-		} else {
-			entropy, err := bip39.EntropyFromMnemonic(seed)
-			fatalIf(err)
-			initBip39(entropy)
+		if s.Seed, err = bip39.NewEntropy(256); err != nil {
+			return
+		}
+		if mnemonic, err = bip39.NewMnemonic(s.Seed); err != nil {
+			return
+		}
+	} else if s.Seed, err = hex.DecodeString(seed); err == nil {
+		if len(s.Seed) != 32 {
+			return nil, "", errors.New("invalid seed length")
 		}
+		s.Bip39, s.Passphrase = false, ""
+	} else if s.Seed, err = bip39.EntropyFromMnemonic(seed); err != nil {
+		return
 	}
-	wallets = append(wallets, wi)
-	wi.initAccounts()
+	wi = &walletInfo{}
+	if err = wi.seal(s, password, kdfName()); err != nil {
+		return
+	}
+	err = wi.initSecret(s)
+	return
+}
+
+// newKeystoreWallet creates a wallet from a keystore envelope, as saved
+// by a keystore file.
+func newKeystoreWallet(e *keystore.Envelope, password []byte) (wi *walletInfo, err error) {
+	wi = &walletInfo{Keystore: e, NoPassword: len(password) == 0}
+	s, err := wi.open(password)
+	if err != nil {
+		return
+	}
+	wi.IsBip39 = s.Bip39
+	err = wi.initSecret(s)
 	return
 }
 
@@ -106,54 +165,188 @@ func (wi *walletInfo) init() {
 		wi.initLedger()
 		return
 	}
-	enc, err := hex.DecodeString(wi.Seed)
-	fatalIf(err)
-	salt, err := hex.DecodeString(wi.Salt)
+	if wi.SignerURL != "" {
+		wi.initRemote()
+		return
+	}
+	err := wi.initSecret(wi.secret("Enter password: "))
 	fatalIf(err)
+}
+
+// secret returns the wallet's seed, from the agent if it holds it, or
+// else by decrypting it with the wallet's password.
+func (wi *walletInfo) secret(prompt string) (s *seedSecret) {
+	if s = wi.agentSecret(); s != nil {
+		return
+	}
 	var password []byte
-	key, _, err := deriveKey(password, salt)
+	if !wi.NoPassword {
+		password = walletPassword(wi, prompt)
+	}
+	s, err := wi.decrypt(password)
 	fatalIf(err)
-	seed, err := decrypt(enc, key)
-	if err != nil {
-		password = readPassword("Enter password: ")
-		key, _, err = deriveKey(password, salt)
-		fatalIf(err)
-		seed, err = decrypt(enc, key)
-		fatalIf(err)
+	return
+}
+
+// locked reports whether the wallet's seed has yet to be decrypted.
+func (wi *walletInfo) locked() bool {
+	return wi.w == nil && !wi.IsLedger && wi.SignerURL == ""
+}
+
+// unlock decrypts the wallet's seed with password and loads the wallet.
+func (wi *walletInfo) unlock(password []byte) (err error) {
+	s, err := wi.decrypt(password)
+	if err == nil {
+		err = wi.initSecret(s)
+	}
+	return
+}
+
+// decrypt decrypts the wallet's seed with password. A seed stored in the
+// format used before keystore envelopes, or with outdated key derivation
+// parameters, is encrypted again and saved.
+func (wi *walletInfo) decrypt(password []byte) (s *seedSecret, err error) {
+	if s, err = wi.open(password); err != nil {
+		return
+	}
+	if wi.Keystore == nil || wi.Keystore.Outdated() {
+		kdf := keystore.Scrypt
+		if wi.Keystore != nil {
+			kdf = wi.Keystore.KDF.Name
+		}
+		if err = wi.seal(s, password, kdf); err != nil {
+			return
+		}
+		err = writeConfig()
+	}
+	return
+}
+
+// seedSecret is the content of a wallet's keystore envelope. The seed is
+// the entropy of the mnemonic of a bip39 wallet.
+type seedSecret struct {
+	Seed       []byte `json:"seed"`
+	Bip39      bool   `json:"bip39,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// open decrypts the wallet's seed.
+func (wi *walletInfo) open(password []byte) (s *seedSecret, err error) {
+	if wi.Keystore != nil {
+		data, err := wi.Keystore.Open(password)
+		if err != nil {
+			return nil, err
+		}
+		s = new(seedSecret)
+		err = json.Unmarshal(data, s)
+		return s, err
+	}
+	// Before keystore envelopes, the bip39 passphrase was the password
+	// unless it had been changed.
+	s = &seedSecret{Bip39: wi.IsBip39}
+	if s.Seed, err = openLegacy(wi.Seed, wi.Salt, password); err != nil {
+		return
 	}
 	if wi.IsBip39 {
-		wi.initBip39(seed, password)
-	} else {
-		wi.initRegularSeed(seed)
+		s.Passphrase = string(password)
+		if wi.Passphrase != "" {
+			passphrase, err := openLegacy(wi.Passphrase, wi.Salt, password)
+			if err != nil {
+				return nil, err
+			}
+			s.Passphrase = string(passphrase)
+		}
+	}
+	return
+}
+
+func openLegacy(data, salt string, password []byte) ([]byte, error) {
+	enc, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	salt2, err := hex.DecodeString(salt)
+	if err != nil {
+		return nil, err
+	}
+	e, err := keystore.Legacy(enc, salt2)
+	if err != nil {
+		return nil, err
+	}
+	return e.Open(password)
+}
+
+// seal encrypts the wallet's seed with password in a keystore envelope.
+func (wi *walletInfo) seal(s *seedSecret, password []byte, kdf string) (err error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	e, err := keystore.Seal(data, password, kdf)
+	if err != nil {
+		return
+	}
+	wi.Keystore, wi.Seed, wi.Salt, wi.Passphrase = e, "", "", ""
+	wi.IsBip39 = s.Bip39
+	wi.NoPassword = len(password) == 0
+	return
+}
+
+func (wi *walletInfo) initSecret(s *seedSecret) (err error) {
+	if s.Bip39 {
+		return wi.initBip39(s.Seed, []byte(s.Passphrase))
 	}
+	return wi.initRegularSeed(s.Seed)
 }
 
-func (wi *walletInfo) initRegularSeed(seed []byte) {
+func (wi *walletInfo) initRegularSeed(seed []byte) (err error) {
 	if len(seed) != 32 {
-		fatal("invalid seed length")
+		return errors.New("invalid seed length")
 	}
-	var err error
-	wi.w, err = wallet.NewWallet(seed)
-	fatalIf(err)
-	wi.w.RPC.URL = rpcURL
-	wi.w.RPCWork.URL = rpcWorkURL
+	if wi.w, err = wallet.NewWallet(seed); err != nil {
+		return
+	}
+	wi.configure()
+	return
 }
 
-func (wi *walletInfo) initBip39(entropy, password []byte) {
+func (wi *walletInfo) initBip39(entropy, password []byte) (err error) {
 	mnemonic, err := bip39.NewMnemonic(entropy)
-	fatalIf(err)
-	wi.w, err = wallet.NewBip39Wallet(mnemonic, string(password))
-	fatalIf(err)
-	wi.w.RPC.URL = rpcURL
-	wi.w.RPCWork.URL = rpcWorkURL
+	if err != nil {
+		return
+	}
+	if wi.w, err = wallet.NewBip39Wallet(mnemonic, string(password)); err != nil {
+		return
+	}
+	wi.configure()
+	return
 }
 
 func (wi *walletInfo) initLedger() {
 	var err error
 	wi.w, err = wallet.NewLedgerWallet()
 	fatalIf(err)
+	wi.configure()
+}
+
+func (wi *walletInfo) initRemote() {
+	var err error
+	c := &signer.Client{URL: wi.SignerURL, Wallet: wi.SignerWallet}
+	if !strings.HasPrefix(wi.SignerURL, "unix:") {
+		c.TLSConfig, err = signer.LoadTLSConfig(wi.SignerCert, wi.SignerKey, wi.SignerCA, false)
+		fatalIf(err)
+	}
+	wi.w, err = wallet.NewSignerWallet(c)
+	fatalIf(err)
+	wi.configure()
+}
+
+func (wi *walletInfo) configure() {
 	wi.w.RPC.URL = rpcURL
 	wi.w.RPCWork.URL = rpcWorkURL
+	wi.w.Store = accountStore()
+	wi.w.Journal = accountJournal()
+	wi.w.Representative = wi.Representative
 }
 
 func (wi *walletInfo) initAccounts() {
@@ -168,14 +361,42 @@ func (wi *walletInfo) initAccounts() {
 	wi.save()
 }
 
+// loadAccounts adds the wallet's saved accounts to its wallet.Wallet.
+func (wi *walletInfo) loadAccounts() (err error) {
+	for _, index := range wi.Accounts {
+		if _, err = wi.w.NewAccount(&index); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// sortedAccounts returns the wallet's saved accounts in index order.
+func (wi *walletInfo) sortedAccounts() (accounts []string) {
+	for address := range wi.Accounts {
+		accounts = append(accounts, address)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return wi.Accounts[accounts[i]] < wi.Accounts[accounts[j]]
+	})
+	return
+}
+
+// save writes the config, exiting on failure. Code which must not exit,
+// such as the serve handlers, calls writeConfig instead.
 func (wi *walletInfo) save() {
-	for i := range wallets {
-		if wi == wallets[i] {
-			viper.Set(fmt.Sprintf("wallets.%d", i), wi)
-			err := viper.WriteConfig()
-			fatalIf(err)
+	err := writeConfig()
+	fatalIf(err)
+}
+
+// hidden reports whether an account is hidden from listings.
+func (wi *walletInfo) hidden(address string) bool {
+	for _, a := range wi.Hidden {
+		if a == address {
+			return true
 		}
 	}
+	return false
 }
 
 func getAccount() (a *wallet.Account) {
@@ -188,7 +409,7 @@ func getAccount() (a *wallet.Account) {
 			}
 		}
 		if walletIndex < 0 {
-			fatal("account not found in any wallet")
+			fatal(newError(codeNotFound, "account not found in any wallet"))
 		}
 	}
 	checkWalletIndex()
@@ -196,7 +417,7 @@ func getAccount() (a *wallet.Account) {
 	wi.init()
 	index, ok := wi.Accounts[walletAccount]
 	if !ok {
-		fatal("account not found in the specified wallet")
+		fatal(newError(codeNotFound, "account not found in the specified wallet"))
 	}
 	a, err := wi.w.NewAccount(&index)
 	fatalIf(err)
@@ -0,0 +1,186 @@
+// Package history fetches the transactions of accounts from a node, so
+// that they can be filtered, merged and exported.
+package history
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+)
+
+// Transaction types.
+const (
+	Send    = "send"
+	Receive = "receive"
+	Change  = "change"
+)
+
+// pageSize is the number of blocks fetched from the node at once.
+const pageSize = 1000
+
+// Entry is a transaction of an account.
+type Entry struct {
+	Account string `json:"account"`
+	Type    string `json:"type"`
+	// Counterpart is the other account of a send or receive, or the new
+	// representative of a change.
+	Counterpart string         `json:"counterpart"`
+	Amount      *rpc.RawAmount `json:"amount"`
+	// Balance is the balance of the account after the transaction.
+	Balance *rpc.RawAmount `json:"balance"`
+	// Time is when the node saw the block, or zero if unknown.
+	Time      time.Time     `json:"time"`
+	Height    uint64        `json:"height"`
+	Hash      rpc.BlockHash `json:"hash"`
+	Confirmed bool          `json:"confirmed"`
+}
+
+// Fetch returns the transactions of account, newest first. Blocks the
+// node saw before since are not fetched, unless since is zero.
+func Fetch(c *rpc.Client, account string, since time.Time) (entries []*Entry, err error) {
+	var head rpc.BlockHash
+	for {
+		history, previous, err := c.AccountHistoryRaw(account, pageSize, head)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range history {
+			e := newEntry(account, &h)
+			if !since.IsZero() && !e.Time.IsZero() && e.Time.Before(since) {
+				return entries, nil
+			}
+			if e.Type != "" {
+				entries = append(entries, e)
+			}
+		}
+		if previous == nil || len(history) == 0 {
+			return entries, nil
+		}
+		head = previous
+	}
+}
+
+// newEntry converts a block of the history of account. The type is
+// empty for blocks which are not transactions, such as epoch blocks.
+func newEntry(account string, h *rpc.AccountHistoryRaw) (e *Entry) {
+	e = &Entry{
+		Account:     account,
+		Counterpart: h.Account,
+		Amount:      h.Amount,
+		Balance:     h.Balance,
+		Height:      h.Height,
+		Hash:        h.Hash,
+		Confirmed:   h.Confirmed,
+	}
+	if h.LocalTimestamp > 0 {
+		e.Time = time.Unix(int64(h.LocalTimestamp), 0).UTC()
+	}
+	t := h.Subtype
+	if h.Type != "state" {
+		t = h.Type
+	}
+	switch t {
+	case Send, Receive:
+		e.Type = t
+	case "open":
+		e.Type = Receive
+	case Change:
+		e.Type = Change
+		e.Counterpart = h.Representative
+	}
+	if e.Amount == nil || e.Type == Change {
+		e.Amount = &rpc.RawAmount{}
+	}
+	return
+}
+
+// Filter selects transactions. Zero fields match everything.
+type Filter struct {
+	From, To     time.Time
+	Types        []string
+	Counterparts []string
+	MinAmount    *big.Int
+	MaxAmount    *big.Int
+}
+
+// Match reports whether e is selected by the filter. Transactions whose
+// time is unknown do not match a date range.
+func (f *Filter) Match(e *Entry) bool {
+	if !f.From.IsZero() && (e.Time.IsZero() || e.Time.Before(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && (e.Time.IsZero() || !e.Time.Before(f.To)) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsType(f.Types, e.Type) {
+		return false
+	}
+	if len(f.Counterparts) > 0 && !containsAccount(f.Counterparts, e.Counterpart) {
+		return false
+	}
+	if f.MinAmount != nil && e.Amount.Cmp(f.MinAmount) < 0 {
+		return false
+	}
+	if f.MaxAmount != nil && e.Amount.Cmp(f.MaxAmount) > 0 {
+		return false
+	}
+	return true
+}
+
+func containsType(types []string, t string) bool {
+	for _, t2 := range types {
+		if t2 == t {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAccount reports whether list contains s. Addresses are compared
+// by public key so that nano_ and xrb_ prefixes are equivalent.
+func containsAccount(list []string, s string) bool {
+	pubkey, err := util.AddressToPubkey(s)
+	for _, s2 := range list {
+		if s2 == s {
+			return true
+		}
+		if err == nil {
+			if pubkey2, err := util.AddressToPubkey(s2); err == nil && string(pubkey2) == string(pubkey) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Select returns the transactions matched by the filter.
+func (f *Filter) Select(entries []*Entry) (selected []*Entry) {
+	for _, e := range entries {
+		if f.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+	return
+}
+
+// Merge merges the histories of several accounts chronologically, oldest
+// first. Transactions at the same time are ordered by account and height.
+func Merge(histories ...[]*Entry) (entries []*Entry) {
+	for _, h := range histories {
+		entries = append(entries, h...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Height < b.Height
+	})
+	return
+}
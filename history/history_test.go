@@ -0,0 +1,127 @@
+package history
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccount = "nano_1sjkhzzeuhup4u9fbd9f77k9puwfbaadymfjnjgbtmiuchqqnmodbwrsnhn9"
+	testPeer    = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	testPeerXrb = "xrb_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	testRep     = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+)
+
+func block(height uint64, subtype, account string, amount, balance int64) map[string]string {
+	hash := make([]byte, 32)
+	hash[31] = byte(height)
+	return map[string]string{
+		"type":            "state",
+		"subtype":         subtype,
+		"account":         account,
+		"representative":  testRep,
+		"amount":          strconv.FormatInt(amount, 10),
+		"balance":         strconv.FormatInt(balance, 10),
+		"local_timestamp": strconv.FormatUint(1600000000+height*100, 10),
+		"height":          strconv.FormatUint(height, 10),
+		"hash":            rpc.BlockHash(hash).String(),
+		"confirmed":       "true",
+	}
+}
+
+// newNode serves the history of testAccount two blocks at a time.
+func newNode(t *testing.T) *httptest.Server {
+	blocks := []map[string]string{
+		block(5, "send", testPeer, 30, 60),
+		block(4, "epoch", testAccount, 0, 90),
+		block(3, "change", testAccount, 0, 90),
+		block(2, "receive", testPeer, 40, 90),
+		block(1, "open", testPeer, 50, 50),
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Action string
+			Head   rpc.BlockHash
+		}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "account_history", req.Action)
+		i := 0
+		if req.Head != nil {
+			i = len(blocks) - int(req.Head[31])
+		}
+		j := i + 2
+		resp := map[string]interface{}{"account": testAccount}
+		if j < len(blocks) {
+			resp["previous"] = blocks[j]["hash"]
+		} else {
+			j = len(blocks)
+		}
+		resp["history"] = blocks[i:j]
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestFetch(t *testing.T) {
+	node := newNode(t)
+	defer node.Close()
+	c := &rpc.Client{URL: node.URL}
+	entries, err := Fetch(c, testAccount, time.Time{})
+	require.Nil(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, Send, entries[0].Type)
+	assert.Equal(t, testPeer, entries[0].Counterpart)
+	assert.Equal(t, "30", entries[0].Amount.String())
+	assert.Equal(t, "60", entries[0].Balance.String())
+	assert.True(t, entries[0].Confirmed)
+	assert.Equal(t, time.Unix(1600000500, 0).UTC(), entries[0].Time)
+	assert.Equal(t, Change, entries[1].Type)
+	assert.Equal(t, testRep, entries[1].Counterpart)
+	assert.Equal(t, "0", entries[1].Amount.String())
+	assert.Equal(t, Receive, entries[2].Type)
+	assert.Equal(t, Receive, entries[3].Type)
+	assert.Equal(t, uint64(1), entries[3].Height)
+
+	entries, err = Fetch(c, testAccount, time.Unix(1600000300, 0))
+	require.Nil(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFilter(t *testing.T) {
+	node := newNode(t)
+	defer node.Close()
+	entries, err := Fetch(&rpc.Client{URL: node.URL}, testAccount, time.Time{})
+	require.Nil(t, err)
+	f := &Filter{Types: []string{Receive}}
+	assert.Len(t, f.Select(entries), 2)
+	f = &Filter{Counterparts: []string{testPeerXrb}}
+	assert.Len(t, f.Select(entries), 3)
+	f = &Filter{MinAmount: big.NewInt(35), MaxAmount: big.NewInt(45)}
+	assert.Len(t, f.Select(entries), 1)
+	f = &Filter{From: time.Unix(1600000200, 0), To: time.Unix(1600000500, 0)}
+	selected := f.Select(entries)
+	require.Len(t, selected, 2)
+	assert.Equal(t, uint64(3), selected[0].Height)
+	assert.Equal(t, uint64(2), selected[1].Height)
+}
+
+func TestMerge(t *testing.T) {
+	at := func(account string, sec int64, height uint64) *Entry {
+		return &Entry{Account: account, Time: time.Unix(sec, 0), Height: height}
+	}
+	a := []*Entry{at("a", 30, 3), at("a", 10, 2), at("a", 10, 1)}
+	b := []*Entry{at("b", 20, 2), at("b", 10, 1)}
+	var got []string
+	for _, e := range Merge(a, b) {
+		got = append(got, e.Account+strconv.FormatUint(e.Height, 10))
+	}
+	assert.Equal(t, []string{"a1", "a2", "b1", "b2", "a3"}, got)
+}
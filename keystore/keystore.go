@@ -0,0 +1,249 @@
+// Package keystore encrypts secrets, such as wallet seeds, under a
+// password in a self-describing envelope. The envelope records its
+// version, the key derivation function and its parameters, and the
+// cipher, so that secrets remain readable as the defaults change.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Version is the current version of the envelope format.
+const Version = 1
+
+// Key derivation functions.
+const (
+	Scrypt   = "scrypt"
+	Argon2id = "argon2id"
+)
+
+// AES256GCM is the cipher used to encrypt secrets.
+const AES256GCM = "aes-256-gcm"
+
+// ErrInvalidPassword is returned when an envelope cannot be opened with
+// the given password.
+var ErrInvalidPassword = errors.New("keystore: invalid password")
+
+// Limits on the key derivation parameters an envelope may ask for, so
+// that a tampered envelope cannot exhaust memory or time. They are well
+// above the defaults.
+const (
+	maxMemory    = 4 << 30 // bytes
+	maxScryptP   = 16
+	maxArgonTime = 64
+)
+
+// KDF describes how the encryption key is derived from the password.
+type KDF struct {
+	Name string `json:"name"`
+	Salt string `json:"salt"`
+	// Scrypt parameters.
+	N int `json:"n,omitempty" yaml:",omitempty"`
+	R int `json:"r,omitempty" yaml:",omitempty"`
+	P int `json:"p,omitempty" yaml:",omitempty"`
+	// Argon2id parameters. Memory is in KiB.
+	Time    uint32 `json:"time,omitempty" yaml:",omitempty"`
+	Memory  uint32 `json:"memory,omitempty" yaml:",omitempty"`
+	Threads uint8  `json:"threads,omitempty" yaml:",omitempty"`
+}
+
+// Envelope is an encrypted secret.
+type Envelope struct {
+	Version    int    `json:"version"`
+	KDF        KDF    `json:"kdf"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// defaultKDF returns the current parameters of a key derivation function.
+func defaultKDF(name string) (kdf KDF, err error) {
+	switch name {
+	case Scrypt:
+		kdf = KDF{Name: Scrypt, N: 1 << 20, R: 8, P: 1}
+	case Argon2id:
+		kdf = KDF{Name: Argon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+	default:
+		err = fmt.Errorf("keystore: unknown key derivation function %q", name)
+	}
+	return
+}
+
+// Seal encrypts data under password, deriving the key with the named
+// key derivation function and its current parameters.
+func Seal(data, password []byte, kdf string) (e *Envelope, err error) {
+	params, err := defaultKDF(kdf)
+	if err != nil {
+		return
+	}
+	return seal(data, password, params)
+}
+
+func seal(data, password []byte, kdf KDF) (e *Envelope, err error) {
+	e = &Envelope{Version: Version, KDF: kdf, Cipher: AES256GCM}
+	salt := make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	e.KDF.Salt = hex.EncodeToString(salt)
+	key, err := e.KDF.key(password)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	e.Nonce = hex.EncodeToString(nonce)
+	e.Ciphertext = hex.EncodeToString(gcm.Seal(nil, nonce, data, nil))
+	return
+}
+
+// Legacy returns the envelope of a secret encrypted by versions of gonano
+// before envelopes, given the encrypted data, prefixed by its nonce, and
+// the salt. Its version is 0.
+func Legacy(enc, salt []byte) (e *Envelope, err error) {
+	if len(enc) < 12 {
+		return nil, errors.New("keystore: invalid ciphertext")
+	}
+	e = &Envelope{
+		KDF:        KDF{Name: Scrypt, Salt: hex.EncodeToString(salt), N: 1 << 20, R: 8, P: 1},
+		Cipher:     AES256GCM,
+		Nonce:      hex.EncodeToString(enc[:12]),
+		Ciphertext: hex.EncodeToString(enc[12:]),
+	}
+	return
+}
+
+// Open decrypts the envelope with password.
+func (e *Envelope) Open(password []byte) (data []byte, err error) {
+	if e.Version > Version {
+		return nil, fmt.Errorf("keystore: unsupported version %d", e.Version)
+	}
+	if e.Cipher != AES256GCM {
+		return nil, fmt.Errorf("keystore: unknown cipher %q", e.Cipher)
+	}
+	nonce, err := hex.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: invalid nonce: %v", err)
+	}
+	ciphertext, err := hex.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: invalid ciphertext: %v", err)
+	}
+	key, err := e.KDF.key(password)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("keystore: invalid nonce")
+	}
+	if data, err = gcm.Open(nil, nonce, ciphertext, nil); err != nil {
+		return nil, ErrInvalidPassword
+	}
+	return
+}
+
+// Outdated reports whether the envelope should be sealed again, as its
+// version or key derivation parameters are older than the current ones.
+func (e *Envelope) Outdated() bool {
+	if e.Version < Version {
+		return true
+	}
+	kdf, err := defaultKDF(e.KDF.Name)
+	if err != nil {
+		return false
+	}
+	switch kdf.Name {
+	case Scrypt:
+		return e.KDF.N < kdf.N || e.KDF.R < kdf.R || e.KDF.P < kdf.P
+	case Argon2id:
+		return e.KDF.Time < kdf.Time || e.KDF.Memory < kdf.Memory
+	}
+	return false
+}
+
+// Load reads an envelope saved as JSON, such as a keystore file.
+func Load(r io.Reader) (e *Envelope, err error) {
+	e = new(Envelope)
+	if err = json.NewDecoder(r).Decode(e); err != nil {
+		return nil, fmt.Errorf("keystore: %v", err)
+	}
+	if e.Version < 1 {
+		return nil, errors.New("keystore: missing version")
+	}
+	return
+}
+
+// Save writes the envelope as indented JSON.
+func (e *Envelope) Save(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// check rejects parameters which are invalid or exceed the limits.
+func (kdf *KDF) check() error {
+	switch kdf.Name {
+	case Scrypt:
+		if kdf.N <= 0 || kdf.R <= 0 || kdf.P <= 0 || kdf.P > maxScryptP ||
+			int64(kdf.N) > maxMemory/128/int64(kdf.R) {
+			return errors.New("keystore: invalid scrypt parameters")
+		}
+	case Argon2id:
+		if kdf.Time == 0 || kdf.Memory == 0 || kdf.Threads == 0 ||
+			kdf.Time > maxArgonTime || uint64(kdf.Memory) > maxMemory/1024 {
+			return errors.New("keystore: invalid argon2id parameters")
+		}
+	default:
+		return fmt.Errorf("keystore: unknown key derivation function %q", kdf.Name)
+	}
+	return nil
+}
+
+func (kdf *KDF) key(password []byte) (key []byte, err error) {
+	salt, err := hex.DecodeString(kdf.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("keystore: invalid salt")
+	}
+	if err = kdf.check(); err != nil {
+		return
+	}
+	switch kdf.Name {
+	case Scrypt:
+		if key, err = scrypt.Key(password, salt, kdf.N, kdf.R, kdf.P, 32); err != nil {
+			return nil, fmt.Errorf("keystore: %v", err)
+		}
+	case Argon2id:
+		key = argon2.IDKey(password, salt, kdf.Time, kdf.Memory, kdf.Threads, 32)
+	default:
+		return nil, fmt.Errorf("keystore: unknown key derivation function %q", kdf.Name)
+	}
+	return
+}
+
+func newGCM(key []byte) (gcm cipher.AEAD, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
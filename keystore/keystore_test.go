@@ -0,0 +1,102 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Cheap parameters, as the defaults take seconds to derive a key.
+var (
+	testScrypt = KDF{Name: Scrypt, N: 1024, R: 8, P: 1}
+	testArgon  = KDF{Name: Argon2id, Time: 1, Memory: 1024, Threads: 1}
+)
+
+func TestSealOpen(t *testing.T) {
+	for _, kdf := range []KDF{testScrypt, testArgon} {
+		e, err := seal([]byte("seed"), []byte("password"), kdf)
+		require.Nil(t, err)
+		assert.Equal(t, Version, e.Version)
+		assert.Equal(t, AES256GCM, e.Cipher)
+		assert.Len(t, e.KDF.Salt, 64)
+		data, err := e.Open([]byte("password"))
+		require.Nil(t, err)
+		assert.Equal(t, []byte("seed"), data)
+		_, err = e.Open([]byte("wrong"))
+		assert.Equal(t, ErrInvalidPassword, err)
+		assert.True(t, e.Outdated())
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	e, err := seal([]byte("seed"), nil, testScrypt)
+	require.Nil(t, err)
+	nonce, _ := hex.DecodeString(e.Nonce)
+	ciphertext, _ := hex.DecodeString(e.Ciphertext)
+	salt, _ := hex.DecodeString(e.KDF.Salt)
+	legacy, err := Legacy(append(nonce, ciphertext...), salt)
+	require.Nil(t, err)
+	assert.Equal(t, 0, legacy.Version)
+	assert.Equal(t, 1<<20, legacy.KDF.N)
+	assert.True(t, legacy.Outdated())
+	legacy.KDF.N = testScrypt.N
+	data, err := legacy.Open(nil)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("seed"), data)
+	_, err = Legacy([]byte("short"), salt)
+	assert.NotNil(t, err)
+}
+
+func TestOutdated(t *testing.T) {
+	for _, name := range []string{Scrypt, Argon2id} {
+		kdf, err := defaultKDF(name)
+		require.Nil(t, err)
+		assert.False(t, (&Envelope{Version: Version, KDF: kdf}).Outdated())
+	}
+	_, err := Seal(nil, nil, "pbkdf2")
+	assert.NotNil(t, err)
+}
+
+func TestLimits(t *testing.T) {
+	for _, kdf := range []KDF{
+		{Name: Scrypt, N: 1 << 23, R: 8, P: 1},
+		{Name: Scrypt, N: 1 << 20, R: 64, P: 1},
+		{Name: Scrypt, N: 1024, R: 8, P: 1 << 20},
+		{Name: Argon2id, Time: 1, Memory: 8 << 20, Threads: 1},
+		{Name: Argon2id, Time: 1 << 30, Memory: 1024, Threads: 1},
+	} {
+		kdf.Salt = "00"
+		_, err := kdf.key([]byte("password"))
+		assert.NotNil(t, err, "%+v", kdf)
+	}
+	for _, name := range []string{Scrypt, Argon2id} {
+		kdf, err := defaultKDF(name)
+		require.Nil(t, err)
+		assert.Nil(t, kdf.check(), name)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	e, err := seal([]byte("seed"), []byte("password"), testArgon)
+	require.Nil(t, err)
+	var buf bytes.Buffer
+	require.Nil(t, e.Save(&buf))
+	e2, err := Load(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, e, e2)
+	data, err := e2.Open([]byte("password"))
+	require.Nil(t, err)
+	assert.Equal(t, []byte("seed"), data)
+
+	e2.Version = Version + 1
+	_, err = e2.Open([]byte("password"))
+	assert.NotNil(t, err)
+	e2.Version, e2.Cipher = Version, "rot13"
+	_, err = e2.Open([]byte("password"))
+	assert.NotNil(t, err)
+	_, err = Load(bytes.NewBufferString(`{"kdf":{"name":"scrypt"}}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,126 @@
+// Package merchant serves invoices which are paid to freshly derived
+// deposit accounts. Payments are received as they are confirmed, funds
+// are swept to a treasury account and status changes are reported to a
+// webhook.
+package merchant
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/uri"
+)
+
+// Status is the payment status of an invoice.
+type Status string
+
+// Invoice statuses.
+const (
+	// StatusPending means nothing has been paid yet.
+	StatusPending Status = "pending"
+	// StatusPartial means less than the amount has been paid.
+	StatusPartial Status = "partial"
+	// StatusPaid means exactly the amount has been paid.
+	StatusPaid Status = "paid"
+	// StatusOverpaid means more than the amount has been paid.
+	StatusOverpaid Status = "overpaid"
+	// StatusExpired means the invoice expired before being paid in full.
+	StatusExpired Status = "expired"
+)
+
+// Invoice is a request for payment to a deposit account.
+type Invoice struct {
+	ID       string          `json:"id"`
+	Account  string          `json:"account"`
+	Index    uint32          `json:"index"`
+	URI      string          `json:"uri"`
+	Amount   *rpc.RawAmount  `json:"amount"`
+	Received *rpc.RawAmount  `json:"received"`
+	Status   Status          `json:"status"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Created  time.Time       `json:"created"`
+	Expires  time.Time       `json:"expires"`
+	// Late is set if a payment was sent after the invoice expired.
+	Late     bool            `json:"late,omitempty"`
+	Payments []Payment       `json:"payments,omitempty"`
+	Sweeps   []rpc.BlockHash `json:"sweeps,omitempty"`
+}
+
+// Payment is a payment received to an invoice's deposit account.
+type Payment struct {
+	Link   rpc.BlockHash  `json:"link"`
+	Hash   rpc.BlockHash  `json:"hash"`
+	Amount *rpc.RawAmount `json:"amount"`
+	// Time is when the node first saw the send block.
+	Time time.Time `json:"time"`
+}
+
+func newInvoice(id, account string, index uint32, amount *big.Int, expires time.Time, metadata json.RawMessage) *Invoice {
+	u := &uri.URI{Scheme: uri.Payment, Address: account, Amount: amount}
+	return &Invoice{
+		ID:       id,
+		Account:  account,
+		Index:    index,
+		URI:      u.String(),
+		Amount:   &rpc.RawAmount{Int: *new(big.Int).Set(amount)},
+		Received: &rpc.RawAmount{},
+		Status:   StatusPending,
+		Metadata: metadata,
+		Created:  time.Now().UTC(),
+		Expires:  expires.UTC(),
+	}
+}
+
+// credit records a payment and updates the status, reporting whether
+// it changed.
+func (inv *Invoice) credit(p Payment) (changed bool) {
+	for _, p2 := range inv.Payments {
+		if p2.Link.String() == p.Link.String() {
+			return false
+		}
+	}
+	old, wasLate := inv.Status, inv.Late
+	inv.Payments = append(inv.Payments, p)
+	inv.Received.Add(&inv.Received.Int, &p.Amount.Int)
+	if p.Time.After(inv.Expires) {
+		inv.Late = true
+	}
+	switch inv.Received.Cmp(&inv.Amount.Int) {
+	case -1:
+		if inv.Status != StatusExpired {
+			inv.Status = StatusPartial
+		}
+	case 0:
+		inv.Status = StatusPaid
+	case 1:
+		inv.Status = StatusOverpaid
+	}
+	return inv.Status != old || inv.Late != wasLate
+}
+
+// expire marks the invoice expired if it is unpaid at time t,
+// reporting whether it changed.
+func (inv *Invoice) expire(t time.Time) (changed bool) {
+	if (inv.Status == StatusPending || inv.Status == StatusPartial) && t.After(inv.Expires) {
+		inv.Status = StatusExpired
+		return true
+	}
+	return false
+}
+
+// settled reports whether no more payments are expected, so that the
+// deposit account should be swept.
+func (inv *Invoice) settled() bool {
+	return inv.Status != StatusPending && inv.Status != StatusPartial
+}
+
+func (inv *Invoice) copy() *Invoice {
+	inv2 := *inv
+	inv2.Amount = &rpc.RawAmount{Int: *new(big.Int).Set(&inv.Amount.Int)}
+	inv2.Received = &rpc.RawAmount{Int: *new(big.Int).Set(&inv.Received.Int)}
+	inv2.Payments = append([]Payment(nil), inv.Payments...)
+	inv2.Sweeps = append([]rpc.BlockHash(nil), inv.Sweeps...)
+	return &inv2
+}
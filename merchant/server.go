@@ -0,0 +1,311 @@
+package merchant
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/wallet"
+)
+
+// DefaultExpiry is how long an invoice is payable for if the request
+// does not specify an expiry.
+const DefaultExpiry = time.Hour
+
+// DefaultSweepRetryInterval is how often failed sweeps are retried if
+// SweepRetryInterval is not set.
+const DefaultSweepRetryInterval = time.Minute
+
+// Server serves invoices. Each invoice is paid to its own account of
+// Wallet, derived from the next index recorded in Store.
+type Server struct {
+	Wallet *wallet.Wallet
+	Store  *Store
+	// Treasury, if set, is the account funds are swept to once an
+	// invoice is paid or expires.
+	Treasury string
+	// Webhook, if set, is notified of invoice status changes.
+	Webhook *Webhook
+	// SweepFailed, if set, is called when sweeping an invoice fails.
+	// Failed sweeps are retried every SweepRetryInterval.
+	SweepFailed        func(inv *Invoice, err error)
+	SweepRetryInterval time.Duration
+	// Token, if set, must be presented as a bearer token by clients.
+	Token        string
+	WebsocketURL string
+	PollInterval time.Duration
+	mu           sync.Mutex
+	receiver     *wallet.Receiver
+	invoices     map[string]string
+	// unswept holds the invoices whose sweep failed. It is only used
+	// by the Run goroutine.
+	unswept map[string]bool
+}
+
+type createRequest struct {
+	Amount *rpc.RawAmount `json:"amount"`
+	// Expiry is the number of seconds the invoice is payable for.
+	Expiry   int64           `json:"expiry"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	switch {
+	case r.URL.Path == "/invoices" && r.Method == http.MethodPost:
+		var req createRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Amount == nil || req.Amount.Sign() <= 0 || req.Expiry < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid invoice"))
+			return
+		}
+		expiry := DefaultExpiry
+		if req.Expiry > 0 {
+			expiry = time.Duration(req.Expiry) * time.Second
+		}
+		inv, err := s.CreateInvoice(&req.Amount.Int, expiry, req.Metadata)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, inv)
+	case strings.HasPrefix(r.URL.Path, "/invoices/") && r.Method == http.MethodGet:
+		if inv := s.Store.Get(strings.TrimPrefix(r.URL.Path, "/invoices/")); inv != nil {
+			writeJSON(w, http.StatusOK, inv)
+		} else {
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+		}
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+// CreateInvoice creates an invoice for amount which expires after
+// expiry, paid to a newly derived account.
+func (s *Server) CreateInvoice(amount *big.Int, expiry time.Duration, metadata json.RawMessage) (inv *Invoice, err error) {
+	index, err := s.Store.nextIndex()
+	if err != nil {
+		return
+	}
+	a, err := s.Wallet.NewAccount(&index)
+	if err != nil {
+		return
+	}
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return
+	}
+	inv = newInvoice(hex.EncodeToString(id), a.Address(), index, amount, time.Now().Add(expiry), metadata)
+	if err = s.Store.put(inv); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.invoices != nil {
+		s.invoices[inv.Account] = inv.ID
+	}
+	if s.receiver != nil {
+		err = s.receiver.Watch(a)
+	}
+	return
+}
+
+// Run watches for payments to invoices until ctx is done.
+func (s *Server) Run(ctx context.Context) (err error) {
+	events := make(chan wallet.ReceiveResult)
+	s.mu.Lock()
+	s.invoices = make(map[string]string)
+	s.unswept = make(map[string]bool)
+	for _, inv := range s.Store.All() {
+		if _, err = s.Wallet.NewAccount(&inv.Index); err != nil {
+			s.mu.Unlock()
+			return
+		}
+		s.invoices[inv.Account] = inv.ID
+		if inv.settled() {
+			// Funds may have been left behind by an earlier run.
+			s.unswept[inv.ID] = true
+		}
+	}
+	s.receiver = &wallet.Receiver{
+		Wallet:       s.Wallet,
+		WebsocketURL: s.WebsocketURL,
+		PollInterval: s.PollInterval,
+		Events:       events,
+	}
+	s.mu.Unlock()
+	if s.Webhook != nil {
+		go s.Webhook.run(ctx)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- s.receiver.Run(ctx) }()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	interval := s.SweepRetryInterval
+	if interval <= 0 {
+		interval = DefaultSweepRetryInterval
+	}
+	retry := time.NewTicker(interval)
+	defer retry.Stop()
+	if err = s.retrySweeps(); err != nil {
+		return
+	}
+	for {
+		select {
+		case err = <-errc:
+			return
+		case e := <-events:
+			if err = s.received(e); err != nil {
+				return
+			}
+		case t := <-ticker.C:
+			if err = s.expire(t); err != nil {
+				return
+			}
+		case <-retry.C:
+			if err = s.retrySweeps(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// received credits a payment to the invoice of the account it was
+// received to.
+func (s *Server) received(e wallet.ReceiveResult) (err error) {
+	if e.Err != nil {
+		// The block is left pending and retried on the next poll.
+		return
+	}
+	s.mu.Lock()
+	id, ok := s.invoices[e.Account]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	p := Payment{
+		Link:   e.Link,
+		Hash:   e.Hash,
+		Amount: &rpc.RawAmount{Int: *new(big.Int).Set(e.Amount)},
+		Time:   s.sentTime(e.Link),
+	}
+	inv, err := s.Store.update(id, func(inv *Invoice) bool { return inv.credit(p) })
+	if err != nil {
+		return
+	}
+	s.notify(inv)
+	return s.sweep(id)
+}
+
+// sentTime returns the time the node first saw the send block link, so
+// that a payment made before expiry is not late if it is received
+// afterwards. The current time is used if the node doesn't know.
+func (s *Server) sentTime(link rpc.BlockHash) time.Time {
+	if info, err := s.Wallet.RPC.BlockInfo(link); err == nil && info.LocalTimestamp > 0 {
+		return time.Unix(int64(info.LocalTimestamp), 0).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// expire expires the invoices which are unpaid at time t.
+func (s *Server) expire(t time.Time) (err error) {
+	for _, inv := range s.Store.All() {
+		inv, err = s.Store.update(inv.ID, func(inv *Invoice) bool { return inv.expire(t) })
+		if err != nil {
+			return
+		}
+		if inv != nil {
+			s.notify(inv)
+			if err = s.sweep(inv.ID); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// retrySweeps retries the sweeps which previously failed.
+func (s *Server) retrySweeps() (err error) {
+	for id := range s.unswept {
+		if err = s.sweep(id); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// sweep sends the balance of a settled invoice's account to the treasury.
+// A failed sweep is reported to SweepFailed and retried later.
+func (s *Server) sweep(id string) (err error) {
+	inv := s.Store.Get(id)
+	if s.Treasury == "" || inv == nil || !inv.settled() {
+		delete(s.unswept, id)
+		return
+	}
+	a := s.Wallet.GetAccount(inv.Account)
+	if a == nil {
+		delete(s.unswept, id)
+		return
+	}
+	st, err := a.State()
+	if err != nil {
+		s.sweepFailed(inv, err)
+		return nil
+	}
+	if st.Balance.Sign() == 0 {
+		delete(s.unswept, id)
+		return
+	}
+	hash, err := a.Send(s.Treasury, &st.Balance.Int)
+	if err != nil {
+		s.sweepFailed(inv, err)
+		return nil
+	}
+	delete(s.unswept, id)
+	_, err = s.Store.update(id, func(inv *Invoice) bool {
+		inv.Sweeps = append(inv.Sweeps, hash)
+		return true
+	})
+	return
+}
+
+func (s *Server) sweepFailed(inv *Invoice, err error) {
+	s.unswept[inv.ID] = true
+	if s.SweepFailed != nil {
+		s.SweepFailed(inv, err)
+	}
+}
+
+func (s *Server) notify(inv *Invoice) {
+	if inv != nil && s.Webhook != nil {
+		s.Webhook.send(inv)
+	}
+}
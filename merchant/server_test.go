@@ -0,0 +1,341 @@
+package merchant_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/merchant"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTreasury = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+
+// fakeNode is a minimal in-memory node implementing the RPCs used to
+// receive and send.
+type fakeNode struct {
+	*httptest.Server
+	mu       sync.Mutex
+	accounts map[string]*rpc.AccountInfo
+	pending  map[string]rpc.HashToPendingMap
+	// sent holds the time each send block was first seen.
+	sent map[string]time.Time
+	// failSends is the number of sends still to be rejected.
+	failSends int
+}
+
+func newFakeNode() *fakeNode {
+	n := &fakeNode{
+		accounts: make(map[string]*rpc.AccountInfo),
+		pending:  make(map[string]rpc.HashToPendingMap),
+		sent:     make(map[string]time.Time),
+	}
+	n.Server = httptest.NewServer(n)
+	return n
+}
+
+// fund adds a pending amount from a made-up source to account.
+func (n *fakeNode) fund(account string, amount int64, hash byte) {
+	n.fundAt(account, amount, hash, time.Now())
+}
+
+// fundAt is like fund, but the send is seen by the node at time t.
+func (n *fakeNode) fundAt(account string, amount int64, hash byte, t time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pending[account] == nil {
+		n.pending[account] = make(rpc.HashToPendingMap)
+	}
+	link := hex.EncodeToString(bytes.Repeat([]byte{hash}, 32))
+	n.sent[link] = t
+	n.pending[account][link] = rpc.AccountPending{
+		Amount: &rpc.RawAmount{Int: *big.NewInt(amount)},
+		Source: testTreasury,
+	}
+}
+
+// pendingTotal returns the total amount pending to account.
+func (n *fakeNode) pendingTotal(account string) int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var total int64
+	for _, p := range n.pending[account] {
+		total += p.Amount.Int64()
+	}
+	return total
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action   string
+		Account  string
+		Accounts []string
+		Block    *rpc.Block
+		Hash     rpc.BlockHash
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var resp interface{}
+	switch req.Action {
+	case "account_info":
+		if info, ok := n.accounts[req.Account]; ok {
+			resp = info
+		} else {
+			resp = map[string]string{"error": "Account not found"}
+		}
+	case "accounts_pending":
+		blocks := make(map[string]rpc.HashToPendingMap)
+		for _, account := range req.Accounts {
+			if len(n.pending[account]) > 0 {
+				blocks[account] = n.pending[account]
+			}
+		}
+		resp = map[string]interface{}{"blocks": blocks}
+	case "block_info":
+		if t, ok := n.sent[hex.EncodeToString(req.Hash)]; ok {
+			resp = map[string]string{"local_timestamp": strconv.FormatInt(t.Unix(), 10)}
+		} else {
+			resp = map[string]string{"error": "Block not found"}
+		}
+	case "work_generate":
+		resp = map[string]string{"work": "0000000000000000", "difficulty": "0000000000000000", "multiplier": "1"}
+	case "process":
+		hash, _ := req.Block.Hash()
+		info, ok := n.accounts[req.Block.Account]
+		if !ok {
+			info = &rpc.AccountInfo{Balance: &rpc.RawAmount{}}
+		}
+		var amount big.Int
+		if amount.Sub(&req.Block.Balance.Int, &info.Balance.Int).Sign() < 0 && n.failSends > 0 {
+			n.failSends--
+			resp = map[string]string{"error": "Fork"}
+			break
+		}
+		if amount.Sign() > 0 {
+			delete(n.pending[req.Block.Account], hex.EncodeToString(req.Block.Link))
+		} else {
+			dest, _ := util.PubkeyToAddress(req.Block.Link)
+			if n.pending[dest] == nil {
+				n.pending[dest] = make(rpc.HashToPendingMap)
+			}
+			amount.Neg(&amount)
+			n.pending[dest][hash.String()] = rpc.AccountPending{Amount: &rpc.RawAmount{Int: amount}}
+		}
+		n.accounts[req.Block.Account] = &rpc.AccountInfo{
+			Frontier:   hash,
+			Balance:    req.Block.Balance,
+			BlockCount: info.BlockCount + 1,
+		}
+		resp = map[string]interface{}{"hash": hash}
+	default:
+		resp = map[string]string{"error": "Unknown command"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newTestServer(t *testing.T, n *fakeNode) *merchant.Server {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := wallet.NewWallet(seed)
+	require.Nil(t, err)
+	w.RPC.URL = n.URL
+	w.RPCWork.URL = n.URL
+	store, err := merchant.OpenStore("", 100)
+	require.Nil(t, err)
+	return &merchant.Server{
+		Wallet:       w,
+		Store:        store,
+		Treasury:     testTreasury,
+		Token:        "secret",
+		PollInterval: 50 * time.Millisecond,
+	}
+}
+
+func request(t *testing.T, s *merchant.Server, method, path, body string) (*httptest.ResponseRecorder, *merchant.Invoice) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var inv merchant.Invoice
+	json.Unmarshal(rec.Body.Bytes(), &inv)
+	return rec, &inv
+}
+
+func TestCreateInvoice(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	s := newTestServer(t, n)
+	rec, inv := request(t, s, http.MethodPost, "/invoices", `{"amount":"1000","expiry":60,"metadata":{"order":42}}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, uint32(100), inv.Index)
+	assert.Equal(t, merchant.StatusPending, inv.Status)
+	assert.Equal(t, "nano:"+inv.Account+"?amount=1000", inv.URI)
+	assert.JSONEq(t, `{"order":42}`, string(inv.Metadata))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), inv.Expires, 5*time.Second)
+	_, inv2 := request(t, s, http.MethodPost, "/invoices", `{"amount":"1000"}`)
+	assert.Equal(t, uint32(101), inv2.Index)
+	assert.NotEqual(t, inv.Account, inv2.Account)
+	rec, inv3 := request(t, s, http.MethodGet, "/invoices/"+inv.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, inv.Account, inv3.Account)
+	rec, _ = request(t, s, http.MethodGet, "/invoices/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = request(t, s, http.MethodPost, "/invoices", `{"amount":"0"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	req := httptest.NewRequest(http.MethodGet, "/invoices/"+inv.ID, nil)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestPayments(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	s := newTestServer(t, n)
+	hooks := make(chan *merchant.Invoice, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, s.Webhook.Sign(body), r.Header.Get(merchant.SignatureHeader))
+		var inv merchant.Invoice
+		require.Nil(t, json.Unmarshal(body, &inv))
+		hooks <- &inv
+	}))
+	defer hook.Close()
+	s.Webhook = merchant.NewWebhook(hook.URL, "hook secret")
+	paid, err := s.CreateInvoice(big.NewInt(100), time.Hour, nil)
+	require.Nil(t, err)
+	over, err := s.CreateInvoice(big.NewInt(100), time.Hour, nil)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	next := func() *merchant.Invoice {
+		select {
+		case inv := <-hooks:
+			return inv
+		case <-time.After(5 * time.Second):
+			t.Fatal("no webhook")
+			return nil
+		}
+	}
+
+	n.fund(paid.Account, 40, 1)
+	inv := next()
+	assert.Equal(t, merchant.StatusPartial, inv.Status)
+	assert.Equal(t, "40", inv.Received.String())
+	assert.Empty(t, inv.Sweeps)
+	n.fund(paid.Account, 60, 2)
+	inv = next()
+	assert.Equal(t, merchant.StatusPaid, inv.Status)
+	assert.Len(t, inv.Payments, 2)
+
+	n.fund(over.Account, 150, 3)
+	inv = next()
+	assert.Equal(t, over.ID, inv.ID)
+	assert.Equal(t, merchant.StatusOverpaid, inv.Status)
+
+	require.Eventually(t, func() bool { return n.pendingTotal(testTreasury) == 250 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, s.Store.Get(paid.ID).Sweeps, 1)
+	assert.Len(t, s.Store.Get(over.ID).Sweeps, 1)
+}
+
+func TestExpiry(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	s := newTestServer(t, n)
+	inv, err := s.CreateInvoice(big.NewInt(100), 0, nil)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	require.Eventually(t, func() bool {
+		return s.Store.Get(inv.ID).Status == merchant.StatusExpired
+	}, 5*time.Second, 10*time.Millisecond)
+	n.fund(inv.Account, 30, 1)
+	require.Eventually(t, func() bool { return n.pendingTotal(testTreasury) == 30 }, 5*time.Second, 10*time.Millisecond)
+	inv = s.Store.Get(inv.ID)
+	assert.Equal(t, merchant.StatusExpired, inv.Status)
+	assert.True(t, inv.Late)
+	assert.Equal(t, "30", inv.Received.String())
+}
+
+func TestSentBeforeExpiry(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	s := newTestServer(t, n)
+	inv, err := s.CreateInvoice(big.NewInt(100), time.Second, nil)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	require.Eventually(t, func() bool {
+		return s.Store.Get(inv.ID).Status == merchant.StatusExpired
+	}, 5*time.Second, 10*time.Millisecond)
+	n.fundAt(inv.Account, 100, 1, inv.Created)
+	require.Eventually(t, func() bool { return n.pendingTotal(testTreasury) == 100 }, 5*time.Second, 10*time.Millisecond)
+	inv = s.Store.Get(inv.ID)
+	assert.Equal(t, merchant.StatusPaid, inv.Status)
+	assert.False(t, inv.Late)
+	assert.Equal(t, inv.Created.Unix(), inv.Payments[0].Time.Unix())
+}
+
+func TestSweepRetry(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	n.failSends = 1
+	s := newTestServer(t, n)
+	s.SweepRetryInterval = 50 * time.Millisecond
+	failed := make(chan string, 10)
+	s.SweepFailed = func(inv *merchant.Invoice, err error) { failed <- inv.ID }
+	inv, err := s.CreateInvoice(big.NewInt(100), time.Hour, nil)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	n.fund(inv.Account, 100, 1)
+	select {
+	case id := <-failed:
+		assert.Equal(t, inv.ID, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("sweep did not fail")
+	}
+	require.Eventually(t, func() bool { return n.pendingTotal(testTreasury) == 100 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(s.Store.Get(inv.ID).Sweeps) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStore(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	s := newTestServer(t, n)
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "invoices.json")
+	s.Store, err = merchant.OpenStore(path, 0)
+	require.Nil(t, err)
+	inv, err := s.CreateInvoice(big.NewInt(100), time.Hour, nil)
+	require.Nil(t, err)
+	store, err := merchant.OpenStore(path, 0)
+	require.Nil(t, err)
+	assert.Equal(t, inv.Account, store.Get(inv.ID).Account)
+	s.Store = store
+	inv2, err := s.CreateInvoice(big.NewInt(100), time.Hour, nil)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(1), inv2.Index)
+}
@@ -0,0 +1,118 @@
+package merchant
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists invoices and the next deposit account index in a JSON
+// file. A store with an empty path is kept in memory only.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data struct {
+		NextIndex uint32              `json:"next_index"`
+		Invoices  map[string]*Invoice `json:"invoices"`
+	}
+}
+
+// OpenStore opens the store at path, creating it if necessary. Deposit
+// accounts are derived from firstIndex onwards.
+func OpenStore(path string, firstIndex uint32) (s *Store, err error) {
+	s = &Store{path: path}
+	s.data.NextIndex = firstIndex
+	s.data.Invoices = make(map[string]*Invoice)
+	if path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &s.data); err != nil {
+		return
+	}
+	if s.data.NextIndex < firstIndex {
+		s.data.NextIndex = firstIndex
+	}
+	return
+}
+
+// Get returns a copy of the invoice with the given ID, or nil.
+func (s *Store) Get(id string) *Invoice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inv := s.data.Invoices[id]; inv != nil {
+		return inv.copy()
+	}
+	return nil
+}
+
+// All returns copies of all the invoices.
+func (s *Store) All() (invoices []*Invoice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inv := range s.data.Invoices {
+		invoices = append(invoices, inv.copy())
+	}
+	return
+}
+
+// nextIndex reserves the index of a new deposit account.
+func (s *Store) nextIndex() (index uint32, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index = s.data.NextIndex
+	s.data.NextIndex++
+	return index, s.save()
+}
+
+// update applies f to the invoice with the given ID and saves the
+// store if f reports a change. The changed invoice is returned.
+func (s *Store) update(id string, f func(inv *Invoice) bool) (inv *Invoice, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inv = s.data.Invoices[id]; inv == nil || !f(inv) {
+		return nil, nil
+	}
+	return inv.copy(), s.save()
+}
+
+func (s *Store) put(inv *Invoice) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Invoices[inv.ID] = inv.copy()
+	return s.save()
+}
+
+func (s *Store) save() (err error) {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(&s.data)
+	if err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), s.path)
+}
@@ -0,0 +1,95 @@
+package merchant
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader is the header carrying the hex HMAC-SHA256 of a
+// webhook body keyed by the webhook secret.
+const SignatureHeader = "X-Gonano-Signature"
+
+// Webhook posts the invoice as JSON to URL whenever its status changes.
+// Deliveries are made in order and retried with backoff on failure.
+type Webhook struct {
+	URL    string
+	Secret string
+	// Retries is the number of times a failed delivery is retried.
+	Retries int
+	Client  *http.Client
+	queue   chan []byte
+}
+
+// NewWebhook creates a webhook posting to url, signed with secret.
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		URL:     url,
+		Secret:  secret,
+		Retries: 5,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan []byte, 1000),
+	}
+}
+
+// Sign returns the signature of body.
+func (h *Webhook) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *Webhook) send(inv *Invoice) {
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+	select {
+	case h.queue <- body:
+	default:
+		// The receiver is too far behind; it can poll the invoice.
+	}
+}
+
+func (h *Webhook) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case body := <-h.queue:
+			backoff := time.Second
+			for i := 0; h.post(body) != nil && i < h.Retries; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func (h *Webhook) post(body []byte) (err error) {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, h.Sign(body))
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		err = fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return
+}
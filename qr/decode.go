@@ -0,0 +1,506 @@
+package qr
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error correction codewords per block and number of blocks, indexed by
+// error correction level (L, M, Q, H) and version.
+var (
+	eccPerBlock = [4][41]int{
+		{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	numBlocks = [4][41]int{
+		{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+)
+
+// grid is a square of modules, true for dark, indexed [y][x].
+type grid [][]bool
+
+// Decode finds a QR code in an image and returns its content. The code
+// must be upright or rotated by a multiple of 90 degrees, as it is in
+// an image file or a screenshot, and surrounded by a light border.
+func Decode(img image.Image) (content []byte, err error) {
+	g, err := sample(img)
+	if err != nil {
+		return
+	}
+	for i := 0; i < 4; i++ {
+		if g.hasFinder(0, 0) && g.hasFinder(len(g)-7, 0) && g.hasFinder(0, len(g)-7) {
+			return g.decode()
+		}
+		g = g.rotate()
+	}
+	return nil, errors.New("qr: finder patterns not found")
+}
+
+// sample thresholds the image and reads the modules at their centres.
+func sample(img image.Image) (g grid, err error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := make([]uint8, w*h)
+	var lo, hi uint8 = 255, 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+			lum[y*w+x] = v
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+	}
+	if hi-lo < 32 {
+		return nil, errors.New("qr: no code found")
+	}
+	thresh := (uint16(lo) + uint16(hi)) / 2
+	dark := func(x, y int) bool { return uint16(lum[y*w+x]) < thresh }
+	x0, y0, x1, y1 := w, h, -1, -1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if dark(x, y) {
+				if x < x0 {
+					x0 = x
+				}
+				if x > x1 {
+					x1 = x
+				}
+				if y < y0 {
+					y0 = y
+				}
+				if y > y1 {
+					y1 = y
+				}
+			}
+		}
+	}
+	if x1 < 0 {
+		return nil, errors.New("qr: no code found")
+	}
+	// Three of the four corners hold a finder pattern, whose edge is
+	// seven modules long, so the median run gives the module size.
+	run := func(x, y, dx int) (n int) {
+		for ; x >= x0 && x <= x1 && dark(x, y); x += dx {
+			n++
+		}
+		return
+	}
+	runs := []int{run(x0, y0, 1), run(x1, y0, -1), run(x0, y1, 1), run(x1, y1, -1)}
+	sort.Ints(runs)
+	module := float64(runs[1]+runs[2]) / 14
+	if module < 1 {
+		return nil, errors.New("qr: no code found")
+	}
+	width, height := float64(x1-x0+1), float64(y1-y0+1)
+	version := int(math.Round((width/module - 17) / 4))
+	if version < 1 || version > 40 || math.Abs(width-height) > 2*module {
+		return nil, errors.New("qr: no code found")
+	}
+	size := 17 + 4*version
+	mx, my := width/float64(size), height/float64(size)
+	g = make(grid, size)
+	for y := range g {
+		g[y] = make([]bool, size)
+		for x := range g[y] {
+			g[y][x] = dark(x0+int((float64(x)+0.5)*mx), y0+int((float64(y)+0.5)*my))
+		}
+	}
+	return
+}
+
+// rotate returns the grid rotated a quarter turn clockwise.
+func (g grid) rotate() grid {
+	n := len(g)
+	r := make(grid, n)
+	for y := range r {
+		r[y] = make([]bool, n)
+		for x := range r[y] {
+			r[y][x] = g[n-1-x][y]
+		}
+	}
+	return r
+}
+
+// hasFinder reports whether a finder pattern has its corner at x, y.
+func (g grid) hasFinder(x0, y0 int) bool {
+	var bad int
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 7; x++ {
+			d := maxInt(absInt(x-3), absInt(y-3))
+			if g[y0+y][x0+x] != (d != 2) {
+				bad++
+			}
+		}
+	}
+	return bad <= 3
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// decode reads the content of an upright grid.
+func (g grid) decode() (content []byte, err error) {
+	size := len(g)
+	version := (size - 17) / 4
+	level, mask, err := g.formatInfo()
+	if err != nil {
+		return
+	}
+	fn := functionModules(version)
+	raw := make([]byte, rawDataModules(version)/8)
+	var i int
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if fn[y][x] || i >= len(raw)*8 {
+					continue
+				}
+				if g[y][x] != masked(mask, x, y) {
+					raw[i>>3] |= 0x80 >> uint(i&7)
+				}
+				i++
+			}
+		}
+	}
+	data, err := deinterleave(raw, version, level)
+	if err != nil {
+		return
+	}
+	return parseSegments(data, version)
+}
+
+// formatInfo reads the error correction level and mask, from whichever
+// copy of the format bits is closest to a valid code.
+func (g grid) formatInfo() (level, mask int, err error) {
+	size := len(g)
+	var a, b int
+	bit := func(v *int, i int, dark bool) {
+		if dark {
+			*v |= 1 << uint(i)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		bit(&a, i, g[i][8])
+	}
+	bit(&a, 6, g[7][8])
+	bit(&a, 7, g[8][8])
+	bit(&a, 8, g[8][7])
+	for i := 9; i < 15; i++ {
+		bit(&a, i, g[8][14-i])
+	}
+	for i := 0; i < 8; i++ {
+		bit(&b, i, g[8][size-1-i])
+	}
+	for i := 8; i < 15; i++ {
+		bit(&b, i, g[size-15+i][8])
+	}
+	best, bestDist := -1, 4
+	for data := 0; data < 32; data++ {
+		code := formatBits(data)
+		for _, v := range []int{a, b} {
+			if d := bitCount(code ^ v); d < bestDist {
+				best, bestDist = data, d
+			}
+		}
+	}
+	if best < 0 {
+		return 0, 0, errors.New("qr: unreadable format information")
+	}
+	// The format bits hold M, L, H, Q as 0 to 3.
+	level = [4]int{1, 0, 3, 2}[best>>3]
+	return level, best & 7, nil
+}
+
+func formatBits(data int) int {
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func bitCount(x int) (n int) {
+	for ; x != 0; x &= x - 1 {
+		n++
+	}
+	return
+}
+
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func alignmentPositions(version int) (pos []int) {
+	if version == 1 {
+		return
+	}
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos = make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, 17+4*version-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return
+}
+
+// functionModules marks the modules which don't hold data.
+func functionModules(version int) (fn grid) {
+	size := 17 + 4*version
+	fn = make(grid, size)
+	for y := range fn {
+		fn[y] = make([]bool, size)
+	}
+	fill := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				fn[y][x] = true
+			}
+		}
+	}
+	// Finders, separators and format information.
+	fill(0, 0, 9, 9)
+	fill(size-8, 0, 8, 9)
+	fill(0, size-8, 9, 8)
+	// Timing patterns.
+	fill(6, 0, 1, size)
+	fill(0, 6, size, 1)
+	pos := alignmentPositions(version)
+	for i, y := range pos {
+		for j, x := range pos {
+			if i == 0 && j == 0 || i == 0 && j == len(pos)-1 || i == len(pos)-1 && j == 0 {
+				continue
+			}
+			fill(x-2, y-2, 5, 5)
+		}
+	}
+	if version >= 7 {
+		fill(size-11, 0, 3, 6)
+		fill(0, size-11, 6, 3)
+	}
+	return
+}
+
+func rawDataModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// deinterleave splits the codewords into blocks, corrects errors in
+// them and returns the data codewords.
+func deinterleave(raw []byte, version, level int) (data []byte, err error) {
+	nblocks, ecc := numBlocks[level][version], eccPerBlock[level][version]
+	shortLen := len(raw) / nblocks
+	numShort := nblocks - len(raw)%nblocks
+	blocks := make([][]byte, nblocks)
+	for j := range blocks {
+		n := shortLen
+		if j >= numShort {
+			n++
+		}
+		blocks[j] = make([]byte, n)
+	}
+	var k int
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range blocks {
+			// Short blocks have no codeword at the end of the data.
+			if i == shortLen-ecc && j < numShort {
+				continue
+			}
+			idx := i
+			if i > shortLen-ecc && j < numShort {
+				idx--
+			}
+			block[idx] = raw[k]
+			k++
+		}
+	}
+	for _, block := range blocks {
+		if err = rsCorrect(block, ecc); err != nil {
+			return
+		}
+		data = append(data, block[:len(block)-ecc]...)
+	}
+	return
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) read(n int) (v int) {
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if r.data[r.pos>>3]&(0x80>>uint(r.pos&7)) != 0 {
+			v |= 1
+		}
+		r.pos++
+	}
+	return
+}
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// parseSegments decodes the numeric, alphanumeric and byte segments.
+func parseSegments(data []byte, version int) (content []byte, err error) {
+	r := &bitReader{data: data}
+	sizeClass := 0
+	if version >= 27 {
+		sizeClass = 2
+	} else if version >= 10 {
+		sizeClass = 1
+	}
+	var sb strings.Builder
+	errInvalid := errors.New("qr: invalid data")
+	for r.remaining() >= 4 {
+		mode := r.read(4)
+		var bits int
+		switch mode {
+		case 0:
+			return []byte(sb.String()), nil
+		case 1:
+			bits = [3]int{10, 12, 14}[sizeClass]
+		case 2:
+			bits = [3]int{9, 11, 13}[sizeClass]
+		case 4:
+			bits = [3]int{8, 16, 16}[sizeClass]
+		case 7:
+			// ECI designators are ignored; content is assumed to be UTF-8.
+			if r.remaining() < 8 {
+				return nil, errInvalid
+			}
+			switch v := r.read(8); {
+			case v&0x80 == 0:
+			case v&0xc0 == 0x80:
+				bits = 8
+			default:
+				bits = 16
+			}
+			if r.remaining() < bits {
+				return nil, errInvalid
+			}
+			r.read(bits)
+			continue
+		default:
+			return nil, errors.New("qr: unsupported data mode")
+		}
+		if r.remaining() < bits {
+			return nil, errInvalid
+		}
+		count := r.read(bits)
+		switch mode {
+		case 1:
+			for ; count >= 3; count -= 3 {
+				if r.remaining() < 10 {
+					return nil, errInvalid
+				}
+				sb.WriteString(padDigits(r.read(10), 3))
+			}
+			if count > 0 {
+				n := 1 + 3*count
+				if r.remaining() < n {
+					return nil, errInvalid
+				}
+				sb.WriteString(padDigits(r.read(n), count))
+			}
+		case 2:
+			for ; count >= 2; count -= 2 {
+				if r.remaining() < 11 {
+					return nil, errInvalid
+				}
+				v := r.read(11)
+				if v >= 45*45 {
+					return nil, errInvalid
+				}
+				sb.WriteByte(alphanumeric[v/45])
+				sb.WriteByte(alphanumeric[v%45])
+			}
+			if count > 0 {
+				if r.remaining() < 6 {
+					return nil, errInvalid
+				}
+				v := r.read(6)
+				if v >= 45 {
+					return nil, errInvalid
+				}
+				sb.WriteByte(alphanumeric[v])
+			}
+		case 4:
+			if r.remaining() < 8*count {
+				return nil, errInvalid
+			}
+			for ; count > 0; count-- {
+				sb.WriteByte(byte(r.read(8)))
+			}
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+func padDigits(v, n int) string {
+	s := strings.Repeat("0", n) + strconv.Itoa(v)
+	return s[len(s)-n:]
+}
@@ -0,0 +1,63 @@
+// Package qr renders addresses, payment URIs and blocks as QR codes,
+// for the terminal or as PNG and SVG images, and decodes QR codes from
+// images so that data can be moved between machines without a network.
+package qr
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Code is a QR code.
+type Code struct {
+	q *qrcode.QRCode
+}
+
+// Encode encodes content as a QR code with medium error correction.
+func Encode(content string) (c *Code, err error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return
+	}
+	return &Code{q: q}, nil
+}
+
+// WriteText writes the code using Unicode block characters, two rows of
+// modules per line. Dark modules are drawn as spaces, which suits
+// terminals with light text on a dark background; invert suits dark
+// text on a light background.
+func (c *Code) WriteText(w io.Writer, invert bool) (err error) {
+	_, err = io.WriteString(w, c.q.ToSmallString(invert))
+	return
+}
+
+// WritePNG writes the code as a PNG image with scale pixels per module.
+func (c *Code) WritePNG(w io.Writer, scale int) (err error) {
+	return png.Encode(w, c.q.Image(-scale))
+}
+
+// WriteSVG writes the code as an SVG image with scale units per module.
+func (c *Code) WriteSVG(w io.Writer, scale int) (err error) {
+	bits := c.q.Bitmap()
+	size := len(bits) * scale
+	if _, err = fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", size, size, len(bits), len(bits)); err != nil {
+		return
+	}
+	if _, err = fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="#fff"/>`+"\n"+`<path fill="#000" d="`); err != nil {
+		return
+	}
+	for y, row := range bits {
+		for x, dark := range row {
+			if dark {
+				if _, err = fmt.Fprintf(w, "M%d %dh1v1h-1z", x, y); err != nil {
+					return
+				}
+			}
+		}
+	}
+	_, err = io.WriteString(w, "\"/>\n</svg>\n")
+	return
+}
@@ -0,0 +1,116 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, content := range []string{
+		"nano:nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny?amount=1000000000000000000000000000000",
+		"nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny",
+		"HELLO WORLD",
+		"0123456789012345",
+		strings.Repeat("nanoblock:{}", 100),
+	} {
+		c, err := Encode(content)
+		require.Nil(t, err)
+		var buf bytes.Buffer
+		require.Nil(t, c.WritePNG(&buf, 3))
+		img, err := png.Decode(&buf)
+		require.Nil(t, err)
+		data, err := Decode(img)
+		require.Nil(t, err, content)
+		assert.Equal(t, content, string(data))
+	}
+}
+
+func TestVersionsAndLevels(t *testing.T) {
+	levels := []qrcode.RecoveryLevel{qrcode.Low, qrcode.Medium, qrcode.High, qrcode.Highest}
+	rng := rand.New(rand.NewSource(1))
+	for version := 1; version <= 40; version++ {
+		for _, level := range levels {
+			q, err := qrcode.NewWithForcedVersion("x", version, level)
+			require.Nil(t, err)
+			// Fill most of the capacity with random bytes.
+			content := make([]byte, q.VersionNumber*4)
+			rng.Read(content)
+			q, err = qrcode.NewWithForcedVersion(string(content), version, level)
+			require.Nil(t, err)
+			data, err := Decode(q.Image(-2))
+			require.Nil(t, err, "version %d level %d", version, level)
+			assert.Equal(t, content, data)
+		}
+	}
+}
+
+func TestDamagedAndRotated(t *testing.T) {
+	content := "nano:nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	q, err := qrcode.New(content, qrcode.Medium)
+	require.Nil(t, err)
+	bits := q.Bitmap()
+	// Flip some data modules in the bottom right corner.
+	n := len(bits)
+	for _, p := range [][2]int{{n - 5, n - 5}, {n - 6, n - 8}, {n - 10, n - 7}, {n - 12, n - 12}} {
+		bits[p[1]][p[0]] = !bits[p[1]][p[0]]
+	}
+	img := image.NewGray(image.Rect(0, 0, n*4, n*4))
+	for y := 0; y < n*4; y++ {
+		for x := 0; x < n*4; x++ {
+			// Rotate a quarter turn while drawing.
+			if !bits[n-1-x/4][y/4] {
+				img.Pix[y*img.Stride+x] = 255
+			}
+		}
+	}
+	data, err := Decode(img)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestReedSolomon(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Blocks like version 1-L: 19 data and 7 error correction codewords.
+	for i := 0; i < 100; i++ {
+		block := make([]byte, 26)
+		copy(block, []byte("rs test block data!"))
+		rsEncode(block, 7)
+		good := append([]byte(nil), block...)
+		for _, k := range rng.Perm(len(block))[:3] {
+			block[k] ^= byte(1 + rng.Intn(255))
+		}
+		require.Nil(t, rsCorrect(block, 7))
+		assert.Equal(t, good, block)
+	}
+}
+
+// rsEncode fills in the last nsym bytes of block with error correction.
+func rsEncode(block []byte, nsym int) {
+	gen := []byte{1}
+	for i := 0; i < nsym; i++ {
+		next := make([]byte, len(gen)+1)
+		for j, c := range gen {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfPow2(i))
+		}
+		gen = next
+	}
+	n := len(block) - nsym
+	rem := make([]byte, len(block))
+	copy(rem, block[:n])
+	for i := 0; i < n; i++ {
+		c := rem[i]
+		for j := 1; j < len(gen); j++ {
+			rem[i+j] ^= gfMul(gen[j], c)
+		}
+	}
+	copy(block[n:], rem[n:])
+}
@@ -0,0 +1,141 @@
+package qr
+
+import "errors"
+
+// Arithmetic in GF(256) with the QR code polynomial x^8+x^4+x^3+x^2+1.
+var gfExp, gfLog [512]byte
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		if x <<= 1; x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfPow2 returns 2^n, where n may be negative.
+func gfPow2(n int) byte {
+	if n %= 255; n < 0 {
+		n += 255
+	}
+	return gfExp[n]
+}
+
+// polyEval evaluates a polynomial with coefficients in ascending order.
+func polyEval(p []byte, x byte) (y byte) {
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return
+}
+
+var errUncorrectable = errors.New("qr: too many errors")
+
+// rsCorrect corrects errors in a Reed-Solomon codeword block in place,
+// where the last nsym bytes are error correction. The first byte is the
+// coefficient of the highest power.
+func rsCorrect(block []byte, nsym int) (err error) {
+	n := len(block)
+	// r(x) has block[n-1-k] as the coefficient of x^k.
+	r := make([]byte, n)
+	for k := range r {
+		r[k] = block[n-1-k]
+	}
+	synd := make([]byte, nsym)
+	var bad bool
+	for i := range synd {
+		synd[i] = polyEval(r, gfPow2(i))
+		bad = bad || synd[i] != 0
+	}
+	if !bad {
+		return
+	}
+	// Berlekamp-Massey finds the error locator polynomial.
+	c, b := []byte{1}, []byte{1}
+	l, m, bd := 0, 1, byte(1)
+	for i := 0; i < nsym; i++ {
+		d := synd[i]
+		for j := 1; j <= l && j < len(c); j++ {
+			d ^= gfMul(c[j], synd[i-j])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		t := append([]byte(nil), c...)
+		coef := gfDiv(d, bd)
+		if len(c) < len(b)+m {
+			c = append(c, make([]byte, len(b)+m-len(c))...)
+		}
+		for j, v := range b {
+			c[j+m] ^= gfMul(coef, v)
+		}
+		if 2*l <= i {
+			l, b, bd, m = i+1-l, t, d, 1
+		} else {
+			m++
+		}
+	}
+	if 2*l > nsym {
+		return errUncorrectable
+	}
+	// The roots of the locator give the error positions.
+	var pos []int
+	for k := 0; k < n; k++ {
+		if polyEval(c, gfPow2(-k)) == 0 {
+			pos = append(pos, k)
+		}
+	}
+	if len(pos) != l {
+		return errUncorrectable
+	}
+	// Forney's algorithm gives the error values.
+	omega := make([]byte, nsym)
+	for i := range omega {
+		for j := 0; j <= i && j < len(c); j++ {
+			omega[i] ^= gfMul(c[j], synd[i-j])
+		}
+	}
+	deriv := make([]byte, len(c))
+	for i := 1; i < len(c); i += 2 {
+		deriv[i-1] = c[i]
+	}
+	for _, k := range pos {
+		xinv := gfPow2(-k)
+		den := polyEval(deriv, xinv)
+		if den == 0 {
+			return errUncorrectable
+		}
+		e := gfMul(gfPow2(k), gfDiv(polyEval(omega, xinv), den))
+		block[n-1-k] ^= e
+	}
+	for k := range r {
+		r[k] = block[n-1-k]
+	}
+	for i := 0; i < nsym; i++ {
+		if polyEval(r, gfPow2(i)) != 0 {
+			return errUncorrectable
+		}
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"errors"
+	"math/big"
+)
+
+// lot is an amount of Nano acquired together, and its cost.
+type lot struct {
+	amount *big.Int
+	cost   *big.Rat
+}
+
+// basis tracks the cost of the Nano held, according to a method.
+type basis struct {
+	method string
+	lots   []*lot
+}
+
+// acquire adds an amount of Nano bought for cost. With the average cost
+// method all Nano is held in a single lot.
+func (b *basis) acquire(amount *big.Int, cost *big.Rat) {
+	if b.method == Average && len(b.lots) > 0 {
+		b.lots[0].amount.Add(b.lots[0].amount, amount)
+		b.lots[0].cost.Add(b.lots[0].cost, cost)
+		return
+	}
+	b.lots = append(b.lots, &lot{new(big.Int).Set(amount), new(big.Rat).Set(cost)})
+}
+
+// dispose removes an amount of Nano and returns its cost, taking from the
+// oldest lots first for FIFO and the newest for LIFO.
+func (b *basis) dispose(amount *big.Int) (cost *big.Rat, err error) {
+	cost = new(big.Rat)
+	remaining := new(big.Int).Set(amount)
+	for remaining.Sign() > 0 {
+		if len(b.lots) == 0 {
+			return nil, errors.New("amount exceeds the Nano acquired")
+		}
+		i := 0
+		if b.method == LIFO {
+			i = len(b.lots) - 1
+		}
+		l := b.lots[i]
+		if l.amount.Cmp(remaining) <= 0 {
+			cost.Add(cost, l.cost)
+			remaining.Sub(remaining, l.amount)
+			b.lots = append(b.lots[:i], b.lots[i+1:]...)
+			continue
+		}
+		part := new(big.Rat).SetFrac(remaining, l.amount)
+		part.Mul(part, l.cost)
+		cost.Add(cost, part)
+		l.cost.Sub(l.cost, part)
+		l.amount.Sub(l.amount, remaining)
+		remaining.SetInt64(0)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prices is a series of prices of one Nano, as given by the user.
+type Prices struct {
+	times  []time.Time
+	prices []*big.Rat
+}
+
+// LoadPrices reads prices from CSV records of a date and the price of one
+// Nano on that date. Dates are in 2006-01-02 (UTC) or RFC 3339 format. A
+// header line is skipped.
+func LoadPrices(r io.Reader) (p *Prices, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("report: %v", err)
+	}
+	type price struct {
+		t     time.Time
+		price *big.Rat
+	}
+	var prices []price
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("report: line %d: expected date and price", i+1)
+		}
+		t, err := parseTime(record[0])
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("report: line %d: invalid date %q", i+1, record[0])
+		}
+		x, ok := new(big.Rat).SetString(strings.TrimSpace(record[1]))
+		if !ok || x.Sign() < 0 {
+			return nil, fmt.Errorf("report: line %d: invalid price %q", i+1, record[1])
+		}
+		prices = append(prices, price{t, x})
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].t.Before(prices[j].t) })
+	p = &Prices{}
+	for _, x := range prices {
+		p.times = append(p.times, x.t)
+		p.prices = append(p.prices, x.price)
+	}
+	return
+}
+
+func parseTime(s string) (t time.Time, err error) {
+	s = strings.TrimSpace(s)
+	if t, err = time.Parse(time.RFC3339, s); err != nil {
+		t, err = time.Parse("2006-01-02", s)
+	}
+	return
+}
+
+// At returns the latest price at or before t.
+func (p *Prices) At(t time.Time) (price *big.Rat, err error) {
+	i := sort.Search(len(p.times), func(i int) bool { return p.times[i].After(t) })
+	if t.IsZero() || i == 0 {
+		return nil, fmt.Errorf("report: no price for %s", t.Format(time.RFC3339))
+	}
+	return p.prices[i-1], nil
+}
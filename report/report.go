@@ -0,0 +1,268 @@
+// Package report summarises the history of a wallet for bookkeeping:
+// balances and flows per period, and the gains realised when Nano leaves
+// the wallet, valued against user supplied prices.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hectorchu/gonano/history"
+	"github.com/hectorchu/gonano/util"
+)
+
+// Cost basis methods.
+const (
+	FIFO    = "fifo"
+	LIFO    = "lifo"
+	Average = "average"
+)
+
+// Period lengths.
+const (
+	Month   = "month"
+	Quarter = "quarter"
+	Year    = "year"
+)
+
+// nano is the number of raw in one Nano.
+var nano = new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+
+// Options configures a report.
+type Options struct {
+	// Accounts are the accounts of the wallet. Transfers between them
+	// are internal.
+	Accounts []string
+	// From and To bound the report, To being exclusive. From defaults
+	// to the start of the period of the first transaction and To to now.
+	From, To time.Time
+	// Period splits the report into months, quarters or years. The
+	// report is a single period if it is empty.
+	Period string
+	// Method is the cost basis method used to compute gains.
+	Method string
+	// Prices are the prices of Nano. Gains are not computed if nil.
+	Prices *Prices
+}
+
+// Transaction is a send or receive of the wallet.
+type Transaction struct {
+	*history.Entry
+	// Internal is set for transfers between the wallet's accounts.
+	Internal bool
+	// Price is the price of one Nano at the time of the transaction and
+	// Value the value of its amount. Cost and Gain are set for external
+	// sends, which dispose of Nano.
+	Price, Value, Cost, Gain *big.Rat
+}
+
+// Period summarises the transactions between Start and End (exclusive).
+type Period struct {
+	Start, End       time.Time
+	Opening, Closing *big.Int
+	// Inflow and Outflow are the amounts received from and sent to
+	// accounts outside the wallet. Internal is the amount sent between
+	// the wallet's accounts.
+	Inflow, Outflow, Internal *big.Int
+	// Proceeds are the value of the outflow, Cost its cost basis and
+	// Gain the difference. They are nil without prices.
+	Proceeds, Cost, Gain *big.Rat
+	Transactions         []*Transaction
+}
+
+// Generate reports on the merged history of a wallet, oldest first, as
+// returned by history.Merge. The whole history is needed, as balances and
+// the cost basis depend on transactions before the report starts.
+func Generate(entries []*history.Entry, opts *Options) (periods []*Period, err error) {
+	g := &generator{
+		own:      make(map[string]bool),
+		balances: make(map[string]*big.Int),
+		basis:    &basis{method: opts.Method},
+		prices:   opts.Prices,
+	}
+	switch opts.Method {
+	case FIFO, LIFO, Average:
+	default:
+		return nil, fmt.Errorf("report: unknown cost basis method %q", opts.Method)
+	}
+	for _, account := range opts.Accounts {
+		pubkey, err := util.AddressToPubkey(account)
+		if err != nil {
+			return nil, err
+		}
+		g.own[string(pubkey)] = true
+	}
+	from, to := opts.From, opts.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to
+		for _, e := range entries {
+			if !e.Time.IsZero() {
+				from = truncate(e.Time.Local(), opts.Period)
+				break
+			}
+		}
+	}
+	if periods, err = split(from, to, opts.Period); err != nil {
+		return
+	}
+	p := -1
+	for _, e := range entries {
+		if e.Time.IsZero() || e.Time.Before(from) {
+			if _, err = g.add(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for p < len(periods) && (p < 0 || !e.Time.Before(periods[p].End)) {
+			g.next(periods, &p)
+		}
+		if p == len(periods) {
+			break
+		}
+		t, err := g.add(e)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			periods[p].record(t)
+		}
+	}
+	for p < len(periods) {
+		g.next(periods, &p)
+	}
+	return
+}
+
+// truncate returns the start of the period, or the day, containing t.
+func truncate(t time.Time, period string) time.Time {
+	y, m, d := t.Date()
+	switch period {
+	case Month:
+		d = 1
+	case Quarter:
+		m, d = (m-1)/3*3+1, 1
+	case Year:
+		m, d = 1, 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// split divides the time from from to to into periods.
+func split(from, to time.Time, period string) (periods []*Period, err error) {
+	if from.After(to) {
+		return nil, errors.New("report: start is after end")
+	}
+	for start := from; start.Before(to); {
+		end := to
+		if period != "" {
+			y, m, _ := start.Date()
+			switch period {
+			case Month:
+				m++
+			case Quarter:
+				m = (m-1)/3*3 + 4
+			case Year:
+				y, m = y+1, 1
+			default:
+				return nil, fmt.Errorf("report: unknown period %q", period)
+			}
+			if next := time.Date(y, m, 1, 0, 0, 0, 0, start.Location()); next.Before(to) {
+				end = next
+			}
+		}
+		periods = append(periods, &Period{
+			Start:    start,
+			End:      end,
+			Inflow:   new(big.Int),
+			Outflow:  new(big.Int),
+			Internal: new(big.Int),
+		})
+		start = end
+	}
+	return
+}
+
+type generator struct {
+	own      map[string]bool
+	balances map[string]*big.Int
+	basis    *basis
+	prices   *Prices
+}
+
+// next closes period p, if any, and opens the next one.
+func (g *generator) next(periods []*Period, p *int) {
+	balance := new(big.Int)
+	for _, b := range g.balances {
+		balance.Add(balance, b)
+	}
+	if *p >= 0 {
+		periods[*p].Closing = balance
+	}
+	if *p++; *p < len(periods) {
+		periods[*p].Opening = new(big.Int).Set(balance)
+		if g.prices != nil {
+			periods[*p].Proceeds = new(big.Rat)
+			periods[*p].Cost = new(big.Rat)
+			periods[*p].Gain = new(big.Rat)
+		}
+	}
+}
+
+// add accounts for a transaction, returning it unless it is a change.
+func (g *generator) add(e *history.Entry) (t *Transaction, err error) {
+	if e.Balance != nil {
+		g.balances[e.Account] = &e.Balance.Int
+	}
+	if e.Type == history.Change {
+		return
+	}
+	t = &Transaction{Entry: e}
+	if pubkey, err := util.AddressToPubkey(e.Counterpart); err == nil {
+		t.Internal = g.own[string(pubkey)]
+	}
+	if t.Internal || g.prices == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		return nil, fmt.Errorf("report: time of block %s is unknown", e.Hash)
+	}
+	if t.Price, err = g.prices.At(e.Time); err != nil {
+		return nil, err
+	}
+	t.Value = new(big.Rat).SetFrac(&e.Amount.Int, nano)
+	t.Value.Mul(t.Value, t.Price)
+	switch e.Type {
+	case history.Receive:
+		g.basis.acquire(&e.Amount.Int, t.Value)
+	case history.Send:
+		if t.Cost, err = g.basis.dispose(&e.Amount.Int); err != nil {
+			return nil, fmt.Errorf("report: block %s: %v", e.Hash, err)
+		}
+		t.Gain = new(big.Rat).Sub(t.Value, t.Cost)
+	}
+	return
+}
+
+func (p *Period) record(t *Transaction) {
+	p.Transactions = append(p.Transactions, t)
+	switch {
+	case t.Internal:
+		if t.Type == history.Send {
+			p.Internal.Add(p.Internal, &t.Amount.Int)
+		}
+	case t.Type == history.Receive:
+		p.Inflow.Add(p.Inflow, &t.Amount.Int)
+	case t.Type == history.Send:
+		p.Outflow.Add(p.Outflow, &t.Amount.Int)
+		if t.Gain != nil {
+			p.Proceeds.Add(p.Proceeds, t.Value)
+			p.Cost.Add(p.Cost, t.Cost)
+			p.Gain.Add(p.Gain, t.Gain)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/history"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	accountA = "nano_1sjkhzzeuhup4u9fbd9f77k9puwfbaadymfjnjgbtmiuchqqnmodbwrsnhn9"
+	accountB = "nano_35hgxkwr59g6a7pa9utpf3qmixxcork9oeo1ode9z7s5zmwcgpbz5a4m33yn"
+	accountX = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+)
+
+func raw(n int64) *rpc.RawAmount {
+	var r rpc.RawAmount
+	r.Mul(big.NewInt(n), nano)
+	return &r
+}
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2021, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func entries() []*history.Entry {
+	entry := func(t time.Time, account, typ, counterpart string, amount, balance int64) *history.Entry {
+		return &history.Entry{
+			Account:     account,
+			Type:        typ,
+			Counterpart: counterpart,
+			Amount:      raw(amount),
+			Balance:     raw(balance),
+			Time:        t,
+		}
+	}
+	return []*history.Entry{
+		entry(date(1, 10), accountA, history.Receive, accountX, 10, 10),
+		entry(date(2, 5), accountA, history.Receive, accountX, 10, 20),
+		entry(date(2, 10), accountA, history.Change, accountX, 0, 20),
+		entry(date(2, 20), accountA, history.Send, accountB, 5, 15),
+		entry(date(2, 21), accountB, history.Receive, accountA, 5, 5),
+		entry(date(3, 15), accountA, history.Send, accountX, 15, 0),
+		entry(date(4, 15), accountB, history.Send, accountX, 5, 0),
+	}
+}
+
+func prices(t *testing.T) *Prices {
+	p, err := LoadPrices(strings.NewReader("date,price\n2021-03-01,3\n2021-01-01,1\n2021-02-01,2\n"))
+	require.Nil(t, err)
+	return p
+}
+
+func TestGenerate(t *testing.T) {
+	opts := &Options{
+		Accounts: []string{accountA, accountB},
+		From:     time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		Period:   Month,
+		Method:   FIFO,
+		Prices:   prices(t),
+	}
+	periods, err := Generate(entries(), opts)
+	require.Nil(t, err)
+	require.Len(t, periods, 2)
+
+	feb := periods[0]
+	assert.Equal(t, opts.From, feb.Start)
+	assert.Equal(t, &raw(10).Int, feb.Opening)
+	assert.Equal(t, &raw(10).Int, feb.Inflow)
+	assert.Equal(t, &raw(5).Int, feb.Internal)
+	assert.Equal(t, 0, feb.Outflow.Sign())
+	assert.Equal(t, &raw(20).Int, feb.Closing)
+	require.Len(t, feb.Transactions, 3)
+	assert.False(t, feb.Transactions[0].Internal)
+	assert.Equal(t, "20", feb.Transactions[0].Value.RatString())
+	assert.True(t, feb.Transactions[1].Internal)
+	assert.True(t, feb.Transactions[2].Internal)
+	assert.Nil(t, feb.Transactions[2].Value)
+
+	mar := periods[1]
+	assert.Equal(t, opts.To, mar.End)
+	assert.Equal(t, &raw(20).Int, mar.Opening)
+	assert.Equal(t, &raw(15).Int, mar.Outflow)
+	assert.Equal(t, &raw(5).Int, mar.Closing)
+	assert.Equal(t, "45", mar.Proceeds.RatString())
+	assert.Equal(t, "20", mar.Cost.RatString())
+	assert.Equal(t, "25", mar.Gain.RatString())
+}
+
+func TestMethods(t *testing.T) {
+	for method, gain := range map[string]string{FIFO: "25", LIFO: "20", Average: "45/2"} {
+		periods, err := Generate(entries(), &Options{
+			Accounts: []string{accountA, accountB},
+			To:       time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			Method:   method,
+			Prices:   prices(t),
+		})
+		require.Nil(t, err)
+		require.Len(t, periods, 1)
+		assert.Equal(t, gain, periods[0].Gain.RatString(), method)
+		assert.Equal(t, 0, periods[0].Opening.Sign())
+		assert.Equal(t, &raw(20).Int, periods[0].Inflow)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := Generate(entries(), &Options{Method: "hifo"})
+	assert.NotNil(t, err)
+	_, err = Generate(entries(), &Options{Method: FIFO, Period: "week"})
+	assert.NotNil(t, err)
+	// The internal transfer is not recognised, so B disposes of Nano it
+	// never acquired.
+	_, err = Generate(entries(), &Options{Accounts: []string{accountA}, Method: FIFO, Prices: prices(t)})
+	assert.NotNil(t, err)
+	p, err := LoadPrices(strings.NewReader("2021-02-01,2\n"))
+	require.Nil(t, err)
+	_, err = Generate(entries(), &Options{Method: FIFO, Prices: p})
+	assert.NotNil(t, err)
+}
+
+func TestPrices(t *testing.T) {
+	p := prices(t)
+	_, err := p.At(time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, err)
+	price, err := p.At(date(2, 28))
+	require.Nil(t, err)
+	assert.Equal(t, "2", price.RatString())
+	price, err = p.At(date(12, 31))
+	require.Nil(t, err)
+	assert.Equal(t, "3", price.RatString())
+	_, err = LoadPrices(strings.NewReader("2021-01-01,abc\n"))
+	assert.NotNil(t, err)
+	_, err = LoadPrices(strings.NewReader("date,price\nyesterday,1\n"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package reps
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Criteria determines which reps are considered healthy.
+type Criteria struct {
+	// MaxShare is the share of online weight (between 0 and 1) above
+	// which a rep is over-concentrated. Zero disables the check.
+	MaxShare float64
+	// MinVersion is the oldest acceptable node major version. If zero,
+	// the newest major version among the reps is used.
+	MinVersion uint64
+	// MaxLag is the most blocks a rep's node may be behind the most
+	// up to date node before it is considered out of sync. Zero
+	// disables the check.
+	MaxLag uint64
+}
+
+// Score is a rep's health as judged against some criteria.
+type Score struct {
+	*Rep
+	// Share is the rep's share of the online weight.
+	Share float64 `json:"share"`
+	// Lag is how many blocks the rep's node is behind, if known.
+	Lag uint64 `json:"lag"`
+	// Issues lists the reasons the rep is unhealthy.
+	Issues []string `json:"issues"`
+}
+
+// Healthy reports whether the rep has no issues.
+func (s *Score) Healthy() bool {
+	return len(s.Issues) == 0
+}
+
+// Rank scores every rep in the snapshot. Healthy reps come first, the
+// least concentrated first so that recommending from the top spreads
+// the network's weight; the rest follow by their number of issues.
+func (s *Snapshot) Rank(c Criteria) (scores []*Score) {
+	var maxBlocks uint64
+	minVersion := c.MinVersion
+	if s.Node != nil {
+		maxBlocks = s.Node.BlockCount
+	}
+	for _, r := range s.Reps {
+		if t := r.Telemetry; t != nil {
+			if t.BlockCount > maxBlocks {
+				maxBlocks = t.BlockCount
+			}
+			if c.MinVersion == 0 && t.MajorVersion > minVersion {
+				minVersion = t.MajorVersion
+			}
+		}
+	}
+	for _, r := range s.Reps {
+		score := &Score{Rep: r}
+		if s.OnlineStake != nil && s.OnlineStake.Sign() > 0 && r.Weight != nil {
+			score.Share, _ = new(big.Rat).SetFrac(&r.Weight.Int, &s.OnlineStake.Int).Float64()
+		}
+		if !r.Online {
+			score.Issues = append(score.Issues, "offline")
+		}
+		if c.MaxShare > 0 && score.Share > c.MaxShare {
+			score.Issues = append(score.Issues, fmt.Sprintf("holds %.2f%% of online weight", score.Share*100))
+		}
+		if t := r.Telemetry; t != nil {
+			if t.MajorVersion < minVersion {
+				score.Issues = append(score.Issues, fmt.Sprintf("outdated version V%d.%d", t.MajorVersion, t.MinorVersion))
+			}
+			score.Lag = maxBlocks - t.BlockCount
+			if c.MaxLag > 0 && score.Lag > c.MaxLag {
+				score.Issues = append(score.Issues, fmt.Sprintf("%d blocks behind", score.Lag))
+			}
+		}
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if len(a.Issues) != len(b.Issues) {
+			return len(a.Issues) < len(b.Issues)
+		}
+		if a.Share != b.Share {
+			return a.Share < b.Share
+		}
+		return a.Account < b.Account
+	})
+	return
+}
@@ -0,0 +1,135 @@
+// Package reps gathers health data about representatives and ranks them,
+// so that wallets can delegate to reps which are online, up to date and
+// not over-concentrated.
+package reps
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+)
+
+// Rep is the health data gathered for a representative.
+type Rep struct {
+	Account    string         `json:"account"`
+	Weight     *rpc.RawAmount `json:"weight"`
+	Online     bool           `json:"online"`
+	Delegators uint64         `json:"delegators"`
+	// Peer is the address of the rep's node, if the node is connected to it.
+	Peer string `json:"peer,omitempty"`
+	// Telemetry is the rep's node telemetry, if it could be fetched.
+	Telemetry *rpc.Telemetry `json:"telemetry,omitempty"`
+}
+
+// Snapshot is the health data of the network's representatives at a
+// point in time. It may be recorded and ranked later.
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	// OnlineStake is the total weight of the online reps.
+	OnlineStake *rpc.RawAmount `json:"online_stake"`
+	// Node is the telemetry of the node the snapshot was taken from.
+	Node *rpc.Telemetry `json:"node,omitempty"`
+	Reps []*Rep         `json:"reps"`
+	// Errors holds, by account, why reps were left out of the snapshot.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Fetch takes a snapshot of the online reps, as well as of the reps in
+// accounts which may be offline.
+func Fetch(c *rpc.Client, accounts []string) (s *Snapshot, err error) {
+	online, err := c.RepresentativesOnline()
+	if err != nil {
+		return
+	}
+	quorum, err := c.ConfirmationQuorum()
+	if err != nil {
+		return
+	}
+	s = &Snapshot{
+		Time:        time.Now().UTC(),
+		OnlineStake: quorum.OnlineStakeTotal,
+	}
+	if node, err := c.Telemetry("", 0); err == nil {
+		s.Node = &node
+	}
+	peers := make(map[string]string)
+	for _, p := range quorum.Peers {
+		peers[p.Account] = p.IP
+	}
+	reps := make(map[string]*Rep)
+	for account, r := range online {
+		reps[account] = &Rep{Account: account, Weight: r.Weight, Online: true}
+	}
+	for _, account := range accounts {
+		if reps[account] != nil {
+			continue
+		}
+		weight, err := c.AccountWeight(account)
+		if err != nil {
+			s.skip(account, err)
+			continue
+		}
+		reps[account] = &Rep{Account: account, Weight: weight}
+	}
+	for _, r := range reps {
+		delegators, err := c.DelegatorsCount(r.Account)
+		if err != nil {
+			s.skip(r.Account, err)
+			continue
+		}
+		r.Delegators = delegators
+		if r.Peer = peers[r.Account]; r.Peer != "" {
+			r.Telemetry = peerTelemetry(c, r.Peer)
+		}
+		s.Reps = append(s.Reps, r)
+	}
+	sort.Slice(s.Reps, func(i, j int) bool { return s.Reps[i].Account < s.Reps[j].Account })
+	return
+}
+
+// skip records why the rep was left out of the snapshot.
+func (s *Snapshot) skip(account string, err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[string]string)
+	}
+	s.Errors[account] = err.Error()
+}
+
+// peerTelemetry fetches the telemetry of the peer at address, returning
+// nil if the peer doesn't respond.
+func peerTelemetry(c *rpc.Client, address string) *rpc.Telemetry {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil
+	}
+	t, err := c.Telemetry(host, uint16(p))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// Load reads a snapshot recorded by Save.
+func Load(r io.Reader) (s *Snapshot, err error) {
+	s = new(Snapshot)
+	if err = json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Save records the snapshot.
+func (s *Snapshot) Save(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
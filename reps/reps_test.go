@@ -0,0 +1,115 @@
+package reps
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rep1 = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+	rep2 = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	rep3 = "nano_3gonano8jnse4zm65jaiki9tk8ry4jtgc1smarinukho6fmbc45k3icsh6en"
+	rep4 = "nano_35s8xxbr4d4mtw5pmiowqyqjh7t4jo5ujmnbstrttcmz5fpgfw4t5esw1s71"
+)
+
+// newNode returns a node whose delegators_count fails for the reps in
+// failing.
+func newNode(failing ...string) *httptest.Server {
+	telemetry := func(blocks, version int) map[string]string {
+		return map[string]string{
+			"block_count":   strconv.Itoa(blocks),
+			"major_version": strconv.Itoa(version),
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		var resp interface{}
+		switch req["action"] {
+		case "representatives_online":
+			resp = map[string]interface{}{"representatives": map[string]interface{}{
+				rep1: map[string]string{"weight": "600"},
+				rep2: map[string]string{"weight": "300"},
+				rep3: map[string]string{"weight": "100"},
+			}}
+		case "confirmation_quorum":
+			resp = map[string]interface{}{
+				"online_weight_quorum_percent": "67",
+				"online_stake_total":           "1000",
+				"peers": []map[string]string{
+					{"account": rep2, "ip": "[::ffff:10.0.0.2]:7075", "weight": "300"},
+					{"account": rep3, "ip": "[::ffff:10.0.0.3]:7075", "weight": "100"},
+				},
+			}
+		case "telemetry":
+			switch req["address"] {
+			case nil:
+				resp = telemetry(1000, 22)
+			case "::ffff:10.0.0.2":
+				resp = telemetry(1000, 22)
+			default:
+				resp = telemetry(900, 21)
+			}
+		case "delegators_count":
+			resp = map[string]string{"count": "5"}
+			for _, account := range failing {
+				if req["account"] == account {
+					resp = map[string]string{"error": "Internal error"}
+				}
+			}
+		case "account_weight":
+			resp = map[string]string{"weight": "0"}
+		default:
+			resp = map[string]string{"error": "Unknown command"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRank(t *testing.T) {
+	n := newNode()
+	defer n.Close()
+	s, err := Fetch(&rpc.Client{URL: n.URL}, []string{rep4})
+	require.Nil(t, err)
+	require.Len(t, s.Reps, 4)
+	var buf bytes.Buffer
+	require.Nil(t, s.Save(&buf))
+	s, err = Load(&buf)
+	require.Nil(t, err)
+
+	scores := s.Rank(Criteria{MaxShare: 0.5, MaxLag: 50})
+	require.Len(t, scores, 4)
+	assert.Equal(t, rep2, scores[0].Account)
+	assert.True(t, scores[0].Healthy())
+	assert.Equal(t, uint64(5), scores[0].Delegators)
+	assert.InDelta(t, 0.3, scores[0].Share, 1e-9)
+	// rep4 is offline, rep1 over-concentrated, rep3 outdated and behind.
+	assert.Equal(t, rep4, scores[1].Account)
+	assert.Equal(t, []string{"offline"}, scores[1].Issues)
+	assert.Equal(t, rep1, scores[2].Account)
+	assert.False(t, scores[2].Healthy())
+	assert.Equal(t, rep3, scores[3].Account)
+	assert.Len(t, scores[3].Issues, 2)
+	assert.Equal(t, uint64(100), scores[3].Lag)
+}
+
+func TestFetchSkipsFailedReps(t *testing.T) {
+	n := newNode(rep3)
+	defer n.Close()
+	s, err := Fetch(&rpc.Client{URL: n.URL}, []string{rep4})
+	require.Nil(t, err)
+	require.Len(t, s.Reps, 3)
+	for _, r := range s.Reps {
+		assert.NotEqual(t, rep3, r.Account)
+	}
+	assert.Equal(t, map[string]string{rep3: "Internal error"}, s.Errors)
+	assert.Len(t, s.Rank(Criteria{}), 3)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 )
@@ -16,15 +15,21 @@ type Client struct {
 	Ctx context.Context
 }
 
+// Error is an error reported by the node.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
 func (c *Client) send(body interface{}) (result []byte, err error) {
 	var buf bytes.Buffer
 	if err = json.NewEncoder(&buf).Encode(body); err != nil {
 		return
 	}
-	if c.Ctx == nil {
-		c.Ctx = context.Background()
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	req, err := http.NewRequestWithContext(c.Ctx, http.MethodPost, c.URL, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, &buf)
 	if err != nil {
 		return
 	}
@@ -48,9 +53,9 @@ func (c *Client) send(body interface{}) (result []byte, err error) {
 		return
 	}
 	if v.Error != "" {
-		err = errors.New(v.Error)
+		err = Error(v.Error)
 	} else if v.Message != "" {
-		err = errors.New(v.Message)
+		err = Error(v.Message)
 	}
 	return buf.Bytes(), err
 }
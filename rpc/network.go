@@ -1,6 +1,9 @@
 package rpc
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strconv"
+)
 
 // AvailableSupply returns how many raw are in the public supply.
 func (c *Client) AvailableSupply() (available *RawAmount, err error) {
@@ -12,3 +15,30 @@ func (c *Client) AvailableSupply() (available *RawAmount, err error) {
 	err = json.Unmarshal(resp, &v)
 	return v.Available, err
 }
+
+// ConfirmationQuorum returns information about the node's consensus
+// parameters, including the representative peers it is connected to.
+func (c *Client) ConfirmationQuorum() (quorum ConfirmationQuorum, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "confirmation_quorum", "peer_details": true})
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(resp, &quorum)
+	return
+}
+
+// Telemetry returns metrics from the peer at address and port, or the
+// averaged metrics of the node's peers if address is empty.
+func (c *Client) Telemetry(address string, port uint16) (telemetry Telemetry, err error) {
+	body := map[string]interface{}{"action": "telemetry"}
+	if address != "" {
+		body["address"] = address
+		body["port"] = strconv.Itoa(int(port))
+	}
+	resp, err := c.send(body)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(resp, &telemetry)
+	return
+}
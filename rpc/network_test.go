@@ -1,9 +1,13 @@
 package rpc_test
 
 import (
+	"encoding/json"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/hectorchu/gonano/rpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -14,3 +18,59 @@ func TestAvailableSupply(t *testing.T) {
 	expectedSupply, _ := new(big.Int).SetString("133000000000000000000000000000000000000", 10)
 	assert.True(t, available.Cmp(expectedSupply) > 0)
 }
+
+// newFakeNode returns a node answering each action with its canned
+// response, recording the requests it is sent.
+func newFakeNode(t *testing.T, responses map[string]string) (c *rpc.Client, requests *[]map[string]interface{}) {
+	requests = new([]map[string]interface{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		*requests = append(*requests, req)
+		resp, ok := responses[req["action"].(string)]
+		if !ok {
+			resp = `{"error":"Unknown command"}`
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(s.Close)
+	return &rpc.Client{URL: s.URL}, requests
+}
+
+func TestConfirmationQuorum(t *testing.T) {
+	c, requests := newFakeNode(t, map[string]string{"confirmation_quorum": `{
+		"quorum_delta": "670",
+		"online_weight_quorum_percent": "67",
+		"online_weight_minimum": "60000",
+		"online_stake_total": "1000",
+		"peers_stake_total": "900",
+		"trended_stake_total": "950",
+		"peers": [{"account": "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny", "ip": "[::ffff:10.0.0.2]:7075", "weight": "300"}]
+	}`})
+	quorum, err := c.ConfirmationQuorum()
+	require.Nil(t, err)
+	assert.Equal(t, true, (*requests)[0]["peer_details"])
+	assert.Equal(t, uint64(67), quorum.OnlineWeightQuorumPercent)
+	assertEqualBig(t, "1000", &quorum.OnlineStakeTotal.Int)
+	require.Len(t, quorum.Peers, 1)
+	assert.Equal(t, "[::ffff:10.0.0.2]:7075", quorum.Peers[0].IP)
+	assertEqualBig(t, "300", &quorum.Peers[0].Weight.Int)
+}
+
+func TestTelemetry(t *testing.T) {
+	c, requests := newFakeNode(t, map[string]string{"telemetry": `{
+		"block_count": "1000",
+		"major_version": "22",
+		"minor_version": "1"
+	}`})
+	telemetry, err := c.Telemetry("", 0)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1000), telemetry.BlockCount)
+	assert.Equal(t, uint64(22), telemetry.MajorVersion)
+	assert.Equal(t, uint64(1), telemetry.MinorVersion)
+	assert.NotContains(t, (*requests)[0], "address")
+	_, err = c.Telemetry("::ffff:10.0.0.2", 7075)
+	require.Nil(t, err)
+	assert.Equal(t, "::ffff:10.0.0.2", (*requests)[1]["address"])
+	assert.Equal(t, "7075", (*requests)[1]["port"])
+}
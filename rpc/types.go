@@ -19,6 +19,7 @@ type AccountHistory struct {
 	LocalTimestamp uint64     `json:"local_timestamp,string"`
 	Height         uint64     `json:"height,string"`
 	Hash           BlockHash  `json:"hash"`
+	Confirmed      bool       `json:"confirmed,string"`
 }
 
 // AccountHistoryRaw reports all parameters of the block itself as seen in
@@ -35,6 +36,7 @@ type AccountHistoryRaw struct {
 	LocalTimestamp uint64     `json:"local_timestamp,string"`
 	Height         uint64     `json:"height,string"`
 	Hash           BlockHash  `json:"hash"`
+	Confirmed      bool       `json:"confirmed,string"`
 	Work           HexData    `json:"work"`
 	Signature      HexData    `json:"signature"`
 }
@@ -57,6 +59,24 @@ type AccountInfo struct {
 	Pending                    *RawAmount `json:"pending"`
 }
 
+// ConfirmationQuorum reports the values used for consensus.
+type ConfirmationQuorum struct {
+	QuorumDelta               *RawAmount   `json:"quorum_delta"`
+	OnlineWeightQuorumPercent uint64       `json:"online_weight_quorum_percent,string"`
+	OnlineWeightMinimum       *RawAmount   `json:"online_weight_minimum"`
+	OnlineStakeTotal          *RawAmount   `json:"online_stake_total"`
+	PeersStakeTotal           *RawAmount   `json:"peers_stake_total"`
+	TrendedStakeTotal         *RawAmount   `json:"trended_stake_total"`
+	Peers                     []QuorumPeer `json:"peers"`
+}
+
+// QuorumPeer is a representative peer contributing to the quorum.
+type QuorumPeer struct {
+	Account string     `json:"account"`
+	IP      string     `json:"ip"`
+	Weight  *RawAmount `json:"weight"`
+}
+
 // Block corresponds to the JSON representation of a block.
 type Block struct {
 	Type           string     `json:"type"`
@@ -68,6 +88,7 @@ type Block struct {
 	LinkAsAccount  string     `json:"link_as_account"`
 	Signature      HexData    `json:"signature"`
 	Work           HexData    `json:"work"`
+	Subtype        string     `json:"subtype,omitempty"`
 }
 
 // Hash calculates the block hash.
@@ -128,6 +149,26 @@ type BlockInfo struct {
 	Subtype        string     `json:"subtype"`
 }
 
+// Telemetry reports metrics about a node.
+type Telemetry struct {
+	BlockCount        uint64    `json:"block_count,string"`
+	CementedCount     uint64    `json:"cemented_count,string"`
+	UncheckedCount    uint64    `json:"unchecked_count,string"`
+	AccountCount      uint64    `json:"account_count,string"`
+	BandwidthCap      uint64    `json:"bandwidth_cap,string"`
+	PeerCount         uint64    `json:"peer_count,string"`
+	ProtocolVersion   uint64    `json:"protocol_version,string"`
+	Uptime            uint64    `json:"uptime,string"`
+	GenesisBlock      BlockHash `json:"genesis_block"`
+	MajorVersion      uint64    `json:"major_version,string"`
+	MinorVersion      uint64    `json:"minor_version,string"`
+	PatchVersion      uint64    `json:"patch_version,string"`
+	PreReleaseVersion uint64    `json:"pre_release_version,string"`
+	Maker             uint64    `json:"maker,string"`
+	Timestamp         uint64    `json:"timestamp,string"`
+	ActiveDifficulty  HexData   `json:"active_difficulty"`
+}
+
 // HexData represents generic hex data.
 type HexData []byte
 
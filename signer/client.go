@@ -0,0 +1,79 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hectorchu/gonano/rpc"
+)
+
+// Client is a wallet.Signer backed by a sign server. URL is either an
+// https:// URL or the path of a Unix socket prefixed with unix:.
+type Client struct {
+	URL       string
+	Wallet    string
+	TLSConfig *tls.Config
+	client    *http.Client
+	baseURL   string
+}
+
+func (c *Client) init() {
+	if c.client != nil {
+		return
+	}
+	transport := &http.Transport{TLSClientConfig: c.TLSConfig}
+	c.baseURL = strings.TrimSuffix(c.URL, "/")
+	if strings.HasPrefix(c.URL, "unix:") {
+		path := strings.TrimPrefix(strings.TrimPrefix(c.URL, "unix:"), "//")
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		c.baseURL = "http://unix"
+	}
+	c.client = &http.Client{Transport: transport}
+}
+
+func (c *Client) send(path string, body, result interface{}) (err error) {
+	c.init()
+	var buf bytes.Buffer
+	if err = json.NewEncoder(&buf).Encode(body); err != nil {
+		return
+	}
+	resp, err := c.client.Post(c.baseURL+path, "application/json", &buf)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var v errorResponse
+		if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			return
+		}
+		return errors.New(v.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Pubkey returns the public key of the account at index.
+func (c *Client) Pubkey(index uint32) (pubkey []byte, err error) {
+	var v pubkeyResponse
+	err = c.send("/pubkey", &pubkeyRequest{Wallet: c.Wallet, Index: index}, &v)
+	return v.Pubkey, err
+}
+
+// SignBlock asks the server to sign block with the key at index.
+func (c *Client) SignBlock(index uint32, block *rpc.Block) (err error) {
+	var v signResponse
+	if err = c.send("/sign", &signRequest{Wallet: c.Wallet, Index: index, Block: block}, &v); err != nil {
+		return
+	}
+	block.Signature = v.Signature
+	return
+}
@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+// Listen listens on address, which is either a host:port pair or the path
+// of a Unix socket prefixed with unix:. TCP listeners require tlsConfig.
+// A Unix socket is only accessible to its owner, and its directory must
+// not be writable by others.
+func Listen(address string, tlsConfig *tls.Config) (l net.Listener, err error) {
+	if strings.HasPrefix(address, "unix:") {
+		return listenUnix(strings.TrimPrefix(strings.TrimPrefix(address, "unix:"), "//"))
+	}
+	if tlsConfig == nil {
+		return nil, errors.New("tls is required for tcp listeners")
+	}
+	return tls.Listen("tcp", address, tlsConfig)
+}
+
+// LoadTLSConfig loads a certificate and key, and a CA used to verify the
+// peer. Server configs require and verify client certificates.
+func LoadTLSConfig(certFile, keyFile, caFile string, server bool) (config *tls.Config, err error) {
+	config = &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in ca file")
+		}
+		if server {
+			config.ClientCAs = pool
+		} else {
+			config.RootCAs = pool
+		}
+	}
+	if server {
+		if caFile == "" {
+			return nil, errors.New("client ca is required")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return
+}
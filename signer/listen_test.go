@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package signer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorchu/gonano/signer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "signer.sock")
+	l, err := signer.Listen("unix:"+path, nil)
+	require.Nil(t, err)
+	fi, err := os.Stat(path)
+	require.Nil(t, err)
+	assert.Zero(t, fi.Mode().Perm()&0077)
+	l.Close()
+	require.Nil(t, os.Chmod(dir, 0777))
+	_, err = signer.Listen("unix:"+filepath.Join(dir, "open.sock"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package signer
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+func listenUnix(path string) (l net.Listener, err error) {
+	dir := filepath.Dir(path)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return
+	}
+	if fi.Mode().Perm()&0002 != 0 {
+		return nil, fmt.Errorf("socket directory %s is writable by others", dir)
+	}
+	// Create the socket without permissions for others, rather than
+	// restricting it afterwards. The umask is process-wide, but is only
+	// ever made stricter here.
+	mask := syscall.Umask(0077)
+	syscall.Umask(mask | 0077)
+	l, err = net.Listen("unix", path)
+	syscall.Umask(mask)
+	return
+}
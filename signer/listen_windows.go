@@ -0,0 +1,16 @@
+package signer
+
+import (
+	"net"
+	"os"
+)
+
+func listenUnix(path string) (l net.Listener, err error) {
+	if l, err = net.Listen("unix", path); err != nil {
+		return
+	}
+	if err = os.Chmod(path, 0600); err != nil {
+		l.Close()
+	}
+	return
+}
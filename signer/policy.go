@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"math/big"
+	"time"
+)
+
+// Policy restricts the blocks a Server is willing to sign.
+type Policy struct {
+	// AllowedDestinations lists the accounts that may be sent to.
+	// If empty, sends to any account are allowed.
+	AllowedDestinations []string
+	// DailyLimit is the maximum amount in raws that each account may
+	// send per UTC day. If nil, there is no limit.
+	DailyLimit *big.Int
+	// Representatives lists the accounts that may be set as representative.
+	// If empty, any representative is allowed.
+	Representatives []string
+}
+
+type spend struct {
+	day    time.Time
+	amount big.Int
+	// blocks holds the amount reserved by each block hash signed today,
+	// so that a block signed again is not charged twice.
+	blocks map[string]*big.Int
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) checkDestination(account string) error {
+	if len(p.AllowedDestinations) > 0 && !contains(p.AllowedDestinations, account) {
+		return denied("destination not allowed")
+	}
+	return nil
+}
+
+func (p *Policy) checkRepresentative(account string) error {
+	if len(p.Representatives) > 0 && !contains(p.Representatives, account) {
+		return denied("representative not allowed")
+	}
+	return nil
+}
+
+// reserve adds the amount sent by the block with hash to the day's total
+// for s, failing if that would exceed the daily limit. A block already
+// reserved today is not charged again.
+func (p *Policy) reserve(s *spend, hash string, amount *big.Int, now time.Time) error {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !s.day.Equal(day) {
+		s.day = day
+		s.amount.SetInt64(0)
+		s.blocks = make(map[string]*big.Int)
+	}
+	if _, ok := s.blocks[hash]; ok {
+		return nil
+	}
+	if p.DailyLimit != nil {
+		var total big.Int
+		if total.Add(&s.amount, amount).Cmp(p.DailyLimit) > 0 {
+			return denied("daily limit exceeded")
+		}
+	}
+	s.amount.Add(&s.amount, amount)
+	s.blocks[hash] = new(big.Int).Set(amount)
+	return nil
+}
+
+// release returns the amount reserved by the block with hash, as it was
+// not signed.
+func (p *Policy) release(s *spend, hash string) {
+	if amount, ok := s.blocks[hash]; ok {
+		s.amount.Sub(&s.amount, amount)
+		delete(s.blocks, hash)
+	}
+}
@@ -0,0 +1,243 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+)
+
+// Server serves sign requests for a set of named wallets.
+type Server struct {
+	Signers map[string]wallet.Signer
+	Policy  Policy
+	// RPC is used to look up the previous block so that the
+	// amount and destination of a send can be determined.
+	RPC rpc.Client
+	// Audit receives a JSON record of every sign request. The record is
+	// written before the signature is returned.
+	Audit io.Writer
+	mu    sync.Mutex
+	spent map[string]*spend
+}
+
+// AuditRecord is written to the audit log for each sign request.
+type AuditRecord struct {
+	Time           time.Time      `json:"time"`
+	Wallet         string         `json:"wallet"`
+	Index          uint32         `json:"index"`
+	Account        string         `json:"account"`
+	Hash           rpc.BlockHash  `json:"hash,omitempty"`
+	Subtype        string         `json:"subtype,omitempty"`
+	Amount         *rpc.RawAmount `json:"amount,omitempty"`
+	Destination    string         `json:"destination,omitempty"`
+	Representative string         `json:"representative,omitempty"`
+	Allowed        bool           `json:"allowed"`
+	Reason         string         `json:"reason,omitempty"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var (
+		resp interface{}
+		err  error
+	)
+	switch r.URL.Path {
+	case "/pubkey":
+		var req pubkeyRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			err = badRequest(err)
+		} else {
+			resp, err = s.pubkey(&req)
+		}
+	case "/sign":
+		var req signRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			err = badRequest(err)
+		} else {
+			resp, err = s.sign(&req)
+		}
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		var e *httpError
+		if errors.As(err, &e) {
+			status = e.status
+		}
+		writeError(w, status, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// httpError is an error reported with an HTTP status other than 500,
+// such as a malformed request or one denied by the policy.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(err error) error {
+	return &httpError{status: http.StatusBadRequest, err: err}
+}
+
+func denied(reason string) error {
+	return &httpError{status: http.StatusForbidden, err: errors.New(reason)}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
+
+func (s *Server) signer(name string) (signer wallet.Signer, err error) {
+	signer, ok := s.Signers[name]
+	if !ok {
+		err = badRequest(errors.New("unknown wallet"))
+	}
+	return
+}
+
+func (s *Server) pubkey(req *pubkeyRequest) (resp *pubkeyResponse, err error) {
+	signer, err := s.signer(req.Wallet)
+	if err != nil {
+		return
+	}
+	pubkey, err := signer.Pubkey(req.Index)
+	if err != nil {
+		return
+	}
+	return &pubkeyResponse{Pubkey: pubkey}, nil
+}
+
+func (s *Server) sign(req *signRequest) (resp *signResponse, err error) {
+	signer, err := s.signer(req.Wallet)
+	if err != nil {
+		return
+	}
+	if req.Block == nil || req.Block.Balance == nil {
+		return nil, badRequest(errors.New("invalid block"))
+	}
+	pubkey, err := signer.Pubkey(req.Index)
+	if err != nil {
+		return
+	}
+	account, err := util.PubkeyToAddress(pubkey)
+	if err != nil {
+		return
+	}
+	rec := &AuditRecord{
+		Time:           time.Now().UTC(),
+		Wallet:         req.Wallet,
+		Index:          req.Index,
+		Account:        req.Block.Account,
+		Representative: req.Block.Representative,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = s.check(account, req.Block, rec); err == nil {
+		if err = signer.SignBlock(req.Index, req.Block); err != nil && s.spent[account] != nil {
+			s.Policy.release(s.spent[account], rec.Hash.String())
+		}
+	}
+	rec.Allowed = err == nil
+	if err != nil {
+		rec.Reason = err.Error()
+	}
+	if err2 := s.audit(rec); err2 != nil {
+		return nil, err2
+	}
+	if err != nil {
+		return
+	}
+	return &signResponse{Signature: req.Block.Signature}, nil
+}
+
+// check decodes the block against its predecessor and applies the policy.
+func (s *Server) check(account string, block *rpc.Block, rec *AuditRecord) (err error) {
+	if block.Account != account {
+		return badRequest(errors.New("block account does not match key"))
+	}
+	if len(block.Previous) != 32 || len(block.Link) != 32 {
+		return badRequest(errors.New("invalid block"))
+	}
+	if rec.Hash, err = block.Hash(); err != nil {
+		return badRequest(err)
+	}
+	var (
+		balance        big.Int
+		representative string
+	)
+	if !bytes.Equal(block.Previous, make([]byte, 32)) {
+		info, err := s.RPC.BlockInfo(block.Previous)
+		if err != nil {
+			return err
+		}
+		if info.BlockAccount != account || info.Contents == nil {
+			return badRequest(errors.New("previous block does not belong to account"))
+		}
+		balance.Set(&info.Balance.Int)
+		representative = info.Contents.Representative
+	}
+	if block.Representative != representative {
+		if err = s.Policy.checkRepresentative(block.Representative); err != nil {
+			return
+		}
+	}
+	var amount big.Int
+	switch amount.Sub(&balance, &block.Balance.Int).Sign() {
+	case 1:
+		rec.Subtype = "send"
+		rec.Amount = &rpc.RawAmount{Int: amount}
+		if rec.Destination, err = util.PubkeyToAddress(block.Link); err != nil {
+			return badRequest(err)
+		}
+		if err = s.Policy.checkDestination(rec.Destination); err != nil {
+			return
+		}
+		if s.spent == nil {
+			s.spent = make(map[string]*spend)
+		}
+		if s.spent[account] == nil {
+			s.spent[account] = new(spend)
+		}
+		return s.Policy.reserve(s.spent[account], rec.Hash.String(), &amount, rec.Time)
+	case -1:
+		rec.Subtype = "receive"
+		rec.Amount = &rpc.RawAmount{Int: *amount.Neg(&amount)}
+	default:
+		rec.Subtype = "change"
+	}
+	return
+}
+
+func (s *Server) audit(rec *AuditRecord) (err error) {
+	if s.Audit == nil {
+		return
+	}
+	if err = json.NewEncoder(s.Audit).Encode(rec); err != nil {
+		return
+	}
+	if f, ok := s.Audit.(interface{ Sync() error }); ok {
+		err = f.Sync()
+	}
+	return
+}
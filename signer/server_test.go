@@ -0,0 +1,164 @@
+package signer_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/signer"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/hectorchu/gonano/wallet/ed25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRep  = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+	testDest = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+)
+
+func newTestServer(t *testing.T, ws wallet.Signer, account string, audit *bytes.Buffer) (*httptest.Server, *httptest.Server) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&rpc.BlockInfo{
+			BlockAccount: account,
+			Amount:       &rpc.RawAmount{},
+			Balance:      &rpc.RawAmount{Int: *big.NewInt(10)},
+			Contents: &rpc.Block{
+				Type:           "state",
+				Account:        account,
+				Previous:       make(rpc.BlockHash, 32),
+				Representative: testRep,
+				Balance:        &rpc.RawAmount{Int: *big.NewInt(10)},
+				Link:           make(rpc.BlockHash, 32),
+			},
+		})
+	}))
+	s := &signer.Server{
+		Signers: map[string]wallet.Signer{"0": ws},
+		Policy: signer.Policy{
+			AllowedDestinations: []string{testDest},
+			DailyLimit:          big.NewInt(10),
+			Representatives:     []string{testRep},
+		},
+		RPC:   rpc.Client{URL: node.URL},
+		Audit: audit,
+	}
+	return httptest.NewServer(s), node
+}
+
+func TestServer(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := wallet.NewWallet(seed)
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	var audit bytes.Buffer
+	server, node := newTestServer(t, w.Signer(), a.Address(), &audit)
+	defer server.Close()
+	defer node.Close()
+	c := &signer.Client{URL: server.URL, Wallet: "0"}
+
+	pubkey, err := c.Pubkey(0)
+	require.Nil(t, err)
+	address, _ := util.PubkeyToAddress(pubkey)
+	assert.Equal(t, a.Address(), address)
+
+	block := func(balance int64, rep string, link []byte) *rpc.Block {
+		return &rpc.Block{
+			Type:           "state",
+			Account:        a.Address(),
+			Previous:       bytes.Repeat([]byte{1}, 32),
+			Representative: rep,
+			Balance:        &rpc.RawAmount{Int: *big.NewInt(balance)},
+			Link:           link,
+		}
+	}
+	dest, _ := util.AddressToPubkey(testDest)
+	other, _ := util.AddressToPubkey(a.Address())
+
+	b := block(4, testRep, dest)
+	require.Nil(t, c.SignBlock(0, b))
+	hash, _ := b.Hash()
+	assert.True(t, ed25519.Verify(pubkey, hash, b.Signature))
+
+	require.Nil(t, c.SignBlock(0, block(4, testRep, dest)))
+	assert.EqualError(t, c.SignBlock(0, block(3, testRep, dest)), "daily limit exceeded")
+	assert.EqualError(t, c.SignBlock(0, block(9, testRep, other)), "destination not allowed")
+	assert.EqualError(t, c.SignBlock(0, block(10, testDest, make([]byte, 32))), "representative not allowed")
+	assert.Nil(t, c.SignBlock(0, block(20, testRep, other)))
+	assert.EqualError(t, (&signer.Client{URL: server.URL, Wallet: "1"}).SignBlock(0, b), "unknown wallet")
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	require.Len(t, lines, 6)
+	var rec signer.AuditRecord
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.True(t, rec.Allowed)
+	assert.Equal(t, "send", rec.Subtype)
+	assert.Equal(t, testDest, rec.Destination)
+	assert.Equal(t, "6", rec.Amount.String())
+	require.Nil(t, json.Unmarshal([]byte(lines[2]), &rec))
+	assert.False(t, rec.Allowed)
+	assert.Equal(t, "daily limit exceeded", rec.Reason)
+}
+
+// failingSigner fails to sign its first block.
+type failingSigner struct {
+	wallet.Signer
+	failed bool
+}
+
+func (s *failingSigner) SignBlock(index uint32, block *rpc.Block) error {
+	if !s.failed {
+		s.failed = true
+		return errors.New("device unavailable")
+	}
+	return s.Signer.SignBlock(index, block)
+}
+
+func TestServerStatus(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := wallet.NewWallet(seed)
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	server, node := newTestServer(t, &failingSigner{Signer: w.Signer()}, a.Address(), new(bytes.Buffer))
+	defer server.Close()
+	defer node.Close()
+	post := func(path, body string) int {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		require.Nil(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, post("/sign", "{"))
+	assert.Equal(t, http.StatusBadRequest, post("/pubkey", `{"wallet":"1"}`))
+
+	dest, _ := util.AddressToPubkey(testDest)
+	block := func(balance int64) *rpc.Block {
+		return &rpc.Block{
+			Type:           "state",
+			Account:        a.Address(),
+			Previous:       bytes.Repeat([]byte{1}, 32),
+			Representative: testRep,
+			Balance:        &rpc.RawAmount{Int: *big.NewInt(balance)},
+			Link:           dest,
+		}
+	}
+	sign := func(b *rpc.Block) int {
+		body, err := json.Marshal(map[string]interface{}{"wallet": "0", "index": 0, "block": b})
+		require.Nil(t, err)
+		return post("/sign", string(body))
+	}
+	// The failed signature releases its reservation of 6 raws.
+	assert.Equal(t, http.StatusInternalServerError, sign(block(4)))
+	assert.Equal(t, http.StatusOK, sign(block(3)))
+	assert.Equal(t, http.StatusForbidden, sign(block(2)))
+}
@@ -0,0 +1,31 @@
+// Package signer implements a protocol for keeping wallet keys in a separate
+// process. A Server holds the keys and enforces a signing Policy, while a
+// Client implements wallet.Signer by forwarding requests to the server.
+package signer
+
+import (
+	"github.com/hectorchu/gonano/rpc"
+)
+
+type pubkeyRequest struct {
+	Wallet string `json:"wallet"`
+	Index  uint32 `json:"index"`
+}
+
+type pubkeyResponse struct {
+	Pubkey rpc.HexData `json:"pubkey"`
+}
+
+type signRequest struct {
+	Wallet string     `json:"wallet"`
+	Index  uint32     `json:"index"`
+	Block  *rpc.Block `json:"block"`
+}
+
+type signResponse struct {
+	Signature rpc.HexData `json:"signature"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
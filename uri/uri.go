@@ -0,0 +1,182 @@
+// Package uri parses and builds the URIs used to pass Nano addresses,
+// payment requests, keys and blocks between wallets:
+//
+//	nano:<address>[?amount=<raw>][&label=<label>][&message=<message>]
+//	nanorep:<address>[?label=<label>][&message=<message>]
+//	nanoseed:<seed>[?label=<label>][&message=<message>][&lastindex=<index>]
+//	nanokey:<private key>[?label=<label>][&message=<message>]
+//	nanoblock:<json block>
+package uri
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+)
+
+// URI schemes.
+const (
+	Payment        = "nano"
+	Representative = "nanorep"
+	Seed           = "nanoseed"
+	Key            = "nanokey"
+	Block          = "nanoblock"
+)
+
+// URI is a parsed Nano URI. Which fields are set depends on the scheme.
+type URI struct {
+	Scheme string
+	// Address is the payment destination or representative.
+	Address string
+	// Key is the seed or private key.
+	Key []byte
+	// Block is the block to be published.
+	Block *rpc.Block
+	// Amount is the amount in raws requested by a payment URI.
+	Amount *big.Int
+	Label  string
+	// Message is a note to the user, not stored on the ledger.
+	Message string
+	// LastIndex is the index of the last account in use with a seed.
+	LastIndex *uint32
+}
+
+// Parse parses and validates a Nano URI. The legacy xrb scheme is
+// accepted as a synonym for nano.
+func Parse(s string) (u *URI, err error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, errors.New("uri: missing scheme")
+	}
+	u = &URI{Scheme: strings.ToLower(s[:i])}
+	s = s[i+1:]
+	if u.Scheme == "xrb" {
+		u.Scheme = Payment
+	}
+	if u.Scheme == Block {
+		return u, u.parseBlock(s)
+	}
+	var query string
+	if i = strings.IndexByte(s, '?'); i >= 0 {
+		s, query = s[:i], s[i+1:]
+	}
+	switch u.Scheme {
+	case Payment, Representative:
+		if _, err = util.AddressToPubkey(s); err != nil {
+			return nil, fmt.Errorf("uri: %v", err)
+		}
+		u.Address = s
+	case Seed, Key:
+		if u.Key, err = hex.DecodeString(s); err != nil || len(u.Key) != 32 {
+			return nil, errors.New("uri: invalid key")
+		}
+	default:
+		return nil, fmt.Errorf("uri: unknown scheme %q", u.Scheme)
+	}
+	if err = u.parseQuery(query); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (u *URI) parseBlock(s string) (err error) {
+	s, err = url.PathUnescape(s)
+	if err != nil {
+		return fmt.Errorf("uri: %v", err)
+	}
+	u.Block = new(rpc.Block)
+	if err = json.Unmarshal([]byte(s), u.Block); err != nil {
+		return fmt.Errorf("uri: invalid block: %v", err)
+	}
+	if _, err = util.AddressToPubkey(u.Block.Account); err != nil {
+		return fmt.Errorf("uri: invalid block account: %v", err)
+	}
+	if _, err = u.Block.Hash(); err != nil {
+		return fmt.Errorf("uri: invalid block: %v", err)
+	}
+	return
+}
+
+func (u *URI) parseQuery(query string) (err error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return fmt.Errorf("uri: %v", err)
+	}
+	for key, v := range values {
+		if len(v) > 1 {
+			return fmt.Errorf("uri: repeated parameter %q", key)
+		}
+		switch key {
+		case "amount":
+			if u.Scheme != Payment {
+				return errors.New("uri: amount is only valid in a payment")
+			}
+			var ok bool
+			if strings.Trim(v[0], "0123456789") != "" {
+				ok = false
+			} else {
+				u.Amount, ok = new(big.Int).SetString(v[0], 10)
+			}
+			if !ok {
+				return fmt.Errorf("uri: invalid amount %q", v[0])
+			}
+		case "label":
+			u.Label = v[0]
+		case "message":
+			u.Message = v[0]
+		case "lastindex":
+			if u.Scheme != Seed {
+				return errors.New("uri: lastindex is only valid with a seed")
+			}
+			index, err := strconv.ParseUint(v[0], 10, 32)
+			if err != nil {
+				return fmt.Errorf("uri: invalid lastindex %q", v[0])
+			}
+			i := uint32(index)
+			u.LastIndex = &i
+		}
+	}
+	return
+}
+
+// String returns the URI in its canonical form.
+func (u *URI) String() string {
+	var s strings.Builder
+	s.WriteString(u.Scheme + ":")
+	switch u.Scheme {
+	case Payment, Representative:
+		s.WriteString(u.Address)
+	case Seed, Key:
+		s.WriteString(strings.ToUpper(hex.EncodeToString(u.Key)))
+	case Block:
+		b, _ := json.Marshal(u.Block)
+		s.WriteString(url.PathEscape(string(b)))
+		return s.String()
+	}
+	sep := "?"
+	param := func(key, value string) {
+		s.WriteString(sep + key + "=" + strings.Replace(url.QueryEscape(value), "+", "%20", -1))
+		sep = "&"
+	}
+	if u.Amount != nil {
+		param("amount", u.Amount.String())
+	}
+	if u.Label != "" {
+		param("label", u.Label)
+	}
+	if u.Message != "" {
+		param("message", u.Message)
+	}
+	if u.LastIndex != nil {
+		param("lastindex", strconv.FormatUint(uint64(*u.LastIndex), 10))
+	}
+	return s.String()
+}
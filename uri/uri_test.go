@@ -0,0 +1,83 @@
+package uri
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAddress = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+
+func TestPayment(t *testing.T) {
+	u, err := Parse("nano:" + testAddress + "?amount=1000000000000000000000000000000&label=Coffee%20Shop&message=Order+42")
+	require.Nil(t, err)
+	assert.Equal(t, Payment, u.Scheme)
+	assert.Equal(t, testAddress, u.Address)
+	assert.Equal(t, "1000000000000000000000000000000", u.Amount.String())
+	assert.Equal(t, "Coffee Shop", u.Label)
+	assert.Equal(t, "Order 42", u.Message)
+	assert.Equal(t, "nano:"+testAddress+"?amount=1000000000000000000000000000000&label=Coffee%20Shop&message=Order%2042", u.String())
+
+	u, err = Parse("xrb:" + testAddress)
+	require.Nil(t, err)
+	assert.Equal(t, "nano:"+testAddress, u.String())
+
+	u = &URI{Scheme: Payment, Address: testAddress, Amount: big.NewInt(5)}
+	u2, err := Parse(u.String())
+	require.Nil(t, err)
+	assert.Equal(t, u, u2)
+}
+
+func TestInvalid(t *testing.T) {
+	for _, s := range []string{
+		testAddress,
+		"bitcoin:" + testAddress,
+		"nano:" + testAddress[:64] + "x",
+		"nano:" + testAddress + "?amount=1.5",
+		"nano:" + testAddress + "?amount=-1",
+		"nano:" + testAddress + "?amount=+1",
+		"nano:" + testAddress + "?amount=1&amount=2",
+		"nanorep:" + testAddress + "?amount=1",
+		"nanoseed:1234",
+		"nanokey:" + testAddress,
+		"nanoseed:0000000000000000000000000000000000000000000000000000000000000001?lastindex=x",
+		"nanoblock:{}",
+	} {
+		_, err := Parse(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	u, err := Parse("nanoseed:0000000000000000000000000000000000000000000000000000000000000001?lastindex=9&label=Savings")
+	require.Nil(t, err)
+	assert.Equal(t, Seed, u.Scheme)
+	assert.Len(t, u.Key, 32)
+	assert.Equal(t, uint32(9), *u.LastIndex)
+	assert.Equal(t, "nanoseed:0000000000000000000000000000000000000000000000000000000000000001?label=Savings&lastindex=9", u.String())
+	u, err = Parse("nanorep:" + testAddress + "?label=My%20Rep")
+	require.Nil(t, err)
+	assert.Equal(t, Representative, u.Scheme)
+	assert.Equal(t, "My Rep", u.Label)
+}
+
+func TestBlock(t *testing.T) {
+	block := &rpc.Block{
+		Type:           "state",
+		Account:        testAddress,
+		Previous:       make(rpc.BlockHash, 32),
+		Representative: testAddress,
+		Balance:        &rpc.RawAmount{Int: *big.NewInt(1)},
+		Link:           make(rpc.BlockHash, 32),
+	}
+	u := &URI{Scheme: Block, Block: block}
+	u2, err := Parse(u.String())
+	require.Nil(t, err)
+	h1, _ := block.Hash()
+	h2, err := u2.Block.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, h1, h2)
+}
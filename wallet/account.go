@@ -4,18 +4,25 @@ import (
 	"encoding/hex"
 	"errors"
 	"math/big"
+	"sync"
 
 	"github.com/hectorchu/gonano/rpc"
 	"github.com/hectorchu/gonano/util"
 )
 
-// Account represents a wallet account.
+// ErrInsufficientFunds is returned when a send exceeds the balance.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Account represents a wallet account. Operations on an account are
+// serialized so that concurrent callers do not fork its chain.
 type Account struct {
 	w              *Wallet
 	index          uint32
-	key, pubkey    []byte
+	pubkey         []byte
 	address        string
+	mu             sync.Mutex
 	representative string
+	st             *AccountState
 }
 
 // Address returns the address of the account.
@@ -37,116 +44,246 @@ func (a *Account) Balance() (balance, pending *big.Int, err error) {
 	return &b.Int, &p.Int, nil
 }
 
+// State returns the locally tracked state of the account.
+func (a *Account) State() (st *AccountState, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.state()
+}
+
+// state returns a copy of the tracked state of the account, loading it
+// from the store or the node if it is not known.
+func (a *Account) state() (st *AccountState, err error) {
+	if err = a.loadState(); err != nil {
+		return &AccountState{Balance: &rpc.RawAmount{}}, err
+	}
+	if a.st == nil {
+		if err = a.reconcile(); err != nil {
+			return &AccountState{Balance: &rpc.RawAmount{}}, err
+		}
+	}
+	return a.st.copy(), nil
+}
+
+func (a *Account) setState(st *AccountState) (err error) {
+	a.st = st
+	if a.w.Store != nil {
+		err = a.w.Store.Save(a.address, st)
+	}
+	return
+}
+
+// Reconcile replaces the tracked state and receivable set of the account
+// with those reported by the node.
+func (a *Account) Reconcile() (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err = a.reconcile(); err != nil {
+		return
+	}
+	pendings, err := a.w.RPC.AccountsPending([]string{a.address}, -1)
+	if err != nil {
+		return
+	}
+	st := a.st.copy()
+	st.Receivable = pendings[a.address]
+	if st.Receivable == nil {
+		st.Receivable = make(rpc.HashToPendingMap)
+	}
+	return a.setState(st)
+}
+
+func (a *Account) reconcile() (err error) {
+	st := &AccountState{Balance: &rpc.RawAmount{}}
+	if a.st != nil {
+		st.Receivable = a.st.Receivable
+	}
+	info, err := a.w.RPC.AccountInfo(a.address)
+	if err != nil && err.Error() != "Account not found" {
+		a.setState(nil)
+		return
+	} else if err == nil {
+		st.Frontier = info.Frontier
+		st.Balance = info.Balance
+		st.Representative = info.Representative
+		st.Height = info.BlockCount
+	}
+	return a.setState(st)
+}
+
+// process publishes block and advances the tracked state. If publishing
+// fails the tracked state is reconciled with the node.
+func (a *Account) process(block *rpc.Block, subtype string) (hash rpc.BlockHash, err error) {
+	if hash, err = a.w.RPC.Process(block, subtype); err != nil {
+		a.reconcile()
+		return
+	}
+	return hash, a.advance(hash, block)
+}
+
+// advance moves the tracked state on to block, which has hash.
+func (a *Account) advance(hash rpc.BlockHash, block *rpc.Block) (err error) {
+	st := &AccountState{Balance: &rpc.RawAmount{}}
+	if a.st != nil {
+		st = a.st.copy()
+	}
+	st.Frontier = hash
+	st.Balance.Set(&block.Balance.Int)
+	st.Representative = block.Representative
+	st.Height++
+	delete(st.Receivable, hex.EncodeToString(block.Link))
+	return a.setState(st)
+}
+
 // Send sends an amount to an account.
 func (a *Account) Send(account string, amount *big.Int) (hash rpc.BlockHash, err error) {
-	block, err := a.SendBlock(account, amount)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	block, err := a.sendBlock(account, amount)
 	if err != nil {
 		return
 	}
 	if block.Work, err = a.w.workGenerate(block.Previous); err != nil {
 		return
 	}
-	return a.w.RPC.Process(block, "send")
+	return a.publishSend(&Payment{Account: account, Amount: amount}, block)
 }
 
 // SendBlock generates a signed send block.
 func (a *Account) SendBlock(account string, amount *big.Int) (block *rpc.Block, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sendBlock(account, amount)
+}
+
+func (a *Account) sendBlock(account string, amount *big.Int) (block *rpc.Block, err error) {
 	link, err := util.AddressToPubkey(account)
 	if err != nil {
 		return
 	}
-	info, err := a.w.RPC.AccountInfo(a.address)
+	st, err := a.state()
 	if err != nil {
 		return
 	}
 	if a.representative == "" {
-		a.representative = info.Representative
+		a.representative = st.Representative
 	}
-	if info.Balance.Sub(&info.Balance.Int, amount).Sign() < 0 {
-		return nil, errors.New("insufficient funds")
+	balance := &rpc.RawAmount{}
+	if balance.Sub(&st.Balance.Int, amount).Sign() < 0 {
+		return nil, ErrInsufficientFunds
 	}
 	block = &rpc.Block{
 		Type:           "state",
 		Account:        a.address,
-		Previous:       info.Frontier,
+		Previous:       st.Frontier,
 		Representative: a.representative,
-		Balance:        info.Balance,
+		Balance:        balance,
 		Link:           link,
 	}
-	return block, a.w.impl.signBlock(a, block)
+	return block, a.w.signer.SignBlock(a.index, block)
+}
+
+// ReceiveResult reports the outcome of receiving a pending block.
+type ReceiveResult struct {
+	Account string
+	Link    rpc.BlockHash
+	Amount  *big.Int
+	Hash    rpc.BlockHash
+	Err     error
 }
 
-// ReceivePendings pockets all pending amounts.
-func (a *Account) ReceivePendings() (err error) {
+// ReceivePendings pockets the pending amounts accepted by the wallet's
+// receive policy, in the policy's order. A block which fails to be
+// received does not stop the rest; the outcome of each is returned.
+func (a *Account) ReceivePendings() (results []ReceiveResult, err error) {
 	pendings, err := a.w.RPC.AccountsPending([]string{a.address}, -1)
 	if err != nil {
 		return
 	}
-	return a.receivePendings(pendings[a.address])
+	return a.receivePendings(pendings[a.address], a.w.ReceivePolicy.MaxBlocks)
 }
 
 // ReceivePending pockets the specified link block.
 func (a *Account) ReceivePending(link rpc.BlockHash) (hash rpc.BlockHash, err error) {
-	info, err := a.w.RPC.AccountInfo(a.address)
-	if err != nil {
-		info.Balance = &rpc.RawAmount{}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st, _ := a.state()
+	amount := st.receivable(hex.EncodeToString(link))
+	if amount == nil {
+		block, err := a.w.RPC.BlockInfo(link)
+		if err != nil {
+			return nil, err
+		}
+		amount = &block.Amount.Int
 	}
-	block, err := a.w.RPC.BlockInfo(link)
+	return a.receivePending(st, link, amount)
+}
+
+// receivePendings receives up to max of the pendings accepted by the
+// receive policy.
+func (a *Account) receivePendings(pendings rpc.HashToPendingMap, max int) (results []ReceiveResult, err error) {
+	blocks, err := a.w.pendingBlocks(pendings)
 	if err != nil {
 		return
 	}
-	info.Balance.Add(&info.Balance.Int, &block.Amount.Int)
-	return a.receivePending(info, link)
-}
-
-func (a *Account) receivePendings(pendings rpc.HashToPendingMap) (err error) {
-	if len(pendings) == 0 {
+	if blocks = limit(blocks, max); len(blocks) == 0 {
 		return
 	}
-	info, err := a.w.RPC.AccountInfo(a.address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err = a.setReceivable(pendings); err != nil {
+		return
+	}
+	for _, b := range blocks {
+		r := ReceiveResult{Account: a.address, Link: b.link, Amount: &b.pending.Amount.Int}
+		st, _ := a.state()
+		r.Hash, r.Err = a.receivePending(st, b.link, r.Amount)
+		results = append(results, r)
+	}
+	return
+}
+
+// setReceivable replaces the tracked receivable set of the account.
+func (a *Account) setReceivable(pendings rpc.HashToPendingMap) (err error) {
+	st, err := a.state()
 	if err != nil {
-		info.Balance = &rpc.RawAmount{}
+		return nil
 	}
+	st.Receivable = make(rpc.HashToPendingMap, len(pendings))
 	for hash, pending := range pendings {
-		var link rpc.BlockHash
-		if link, err = hex.DecodeString(hash); err != nil {
-			return
-		}
-		info.Balance.Add(&info.Balance.Int, &pending.Amount.Int)
-		if info.Frontier, err = a.receivePending(info, link); err != nil {
-			return
-		}
+		st.Receivable[hash] = pending
 	}
-	return
+	return a.setState(st)
 }
 
-func (a *Account) receivePending(info rpc.AccountInfo, link rpc.BlockHash) (hash rpc.BlockHash, err error) {
-	workHash := info.Frontier
-	if info.Frontier == nil {
-		info.Frontier = make(rpc.BlockHash, 32)
+func (a *Account) receivePending(st *AccountState, link rpc.BlockHash, amount *big.Int) (hash rpc.BlockHash, err error) {
+	workHash := st.Frontier
+	if st.Frontier == nil {
+		st.Frontier = make(rpc.BlockHash, 32)
 		workHash = a.pubkey
 	}
 	if a.representative == "" {
-		a.representative = info.Representative
+		a.representative = st.Representative
 		if a.representative == "" {
-			a.representative = "nano_3gonano8jnse4zm65jaiki9tk8ry4jtgc1smarinukho6fmbc45k3icsh6en"
+			a.representative = a.w.defaultRep()
 		}
 	}
 	block := &rpc.Block{
 		Type:           "state",
 		Account:        a.address,
-		Previous:       info.Frontier,
+		Previous:       st.Frontier,
 		Representative: a.representative,
-		Balance:        info.Balance,
+		Balance:        &rpc.RawAmount{},
 		Link:           link,
 	}
-	if err = a.w.impl.signBlock(a, block); err != nil {
+	block.Balance.Add(&st.Balance.Int, amount)
+	if err = a.w.signer.SignBlock(a.index, block); err != nil {
 		return
 	}
 	if block.Work, err = a.w.workGenerateReceive(workHash); err != nil {
 		return
 	}
-	return a.w.RPC.Process(block, "receive")
+	return a.process(block, "receive")
 }
 
 // SetRep sets the account's representative for future blocks.
@@ -154,31 +291,36 @@ func (a *Account) SetRep(representative string) (err error) {
 	if _, err = util.AddressToPubkey(representative); err != nil {
 		return
 	}
+	a.mu.Lock()
 	a.representative = representative
+	a.mu.Unlock()
 	return
 }
 
 // ChangeRep changes the account's representative.
 func (a *Account) ChangeRep(representative string) (hash rpc.BlockHash, err error) {
-	info, err := a.w.RPC.AccountInfo(a.address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st, err := a.state()
 	if err != nil {
 		return
 	}
 	block := &rpc.Block{
 		Type:           "state",
 		Account:        a.address,
-		Previous:       info.Frontier,
+		Previous:       st.Frontier,
 		Representative: representative,
-		Balance:        info.Balance,
+		Balance:        &rpc.RawAmount{},
 		Link:           make(rpc.BlockHash, 32),
 	}
-	if err = a.w.impl.signBlock(a, block); err != nil {
+	block.Balance.Set(&st.Balance.Int)
+	if err = a.w.signer.SignBlock(a.index, block); err != nil {
 		return
 	}
-	if block.Work, err = a.w.workGenerate(info.Frontier); err != nil {
+	if block.Work, err = a.w.workGenerate(st.Frontier); err != nil {
 		return
 	}
-	if hash, err = a.w.RPC.Process(block, "change"); err == nil {
+	if hash, err = a.process(block, "change"); err == nil {
 		a.representative = representative
 	}
 	return
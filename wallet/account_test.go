@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentSends(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	receiveAll(t, a)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a2, _ := w.NewAccount(&a.index)
+			_, errs[i] = a2.Send(b.Address(), big.NewInt(10))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.Nil(t, err)
+	}
+	balance, pending, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), balance.Int64())
+	assert.Equal(t, int64(0), pending.Int64())
+	_, pending, err = b.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), pending.Int64())
+	assert.Equal(t, 1, n.calls["account_info"])
+}
+
+func TestStore(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(filepath.Join(dir, "state.json"))
+	require.Nil(t, err)
+	w := newTestWallet(t, n)
+	w.Store = store
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	link := n.fund(a.Address(), 100, 1)
+	hash, err := a.ReceivePending(link)
+	require.Nil(t, err)
+
+	store, err = NewFileStore(filepath.Join(dir, "state.json"))
+	require.Nil(t, err)
+	st, err := store.Load(a.Address())
+	require.Nil(t, err)
+	require.NotNil(t, st)
+	assert.Equal(t, hash, st.Frontier)
+	assert.Equal(t, int64(100), st.Balance.Int64())
+	assert.Equal(t, uint64(1), st.Height)
+
+	w = newTestWallet(t, n)
+	w.Store = store
+	a, err = w.NewAccount(nil)
+	require.Nil(t, err)
+	calls := n.calls["account_info"]
+	_, err = a.Send(a.Address(), big.NewInt(10))
+	require.Nil(t, err)
+	assert.Equal(t, calls, n.calls["account_info"])
+}
+
+func TestStoreShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	s1, err := NewFileStore(path)
+	require.Nil(t, err)
+	s2, err := NewFileStore(path)
+	require.Nil(t, err)
+	st := func(balance int64) *AccountState {
+		return &AccountState{Balance: &rpc.RawAmount{Int: *big.NewInt(balance)}}
+	}
+	require.Nil(t, s1.Save("a", st(1)))
+	require.Nil(t, s2.Save("b", st(2)))
+	require.Nil(t, s1.Save("c", st(3)))
+	s3, err := NewFileStore(path)
+	require.Nil(t, err)
+	for account, balance := range map[string]int64{"a": 1, "b": 2, "c": 3} {
+		st, err := s3.Load(account)
+		require.Nil(t, err)
+		require.NotNil(t, st, account)
+		assert.Equal(t, balance, st.Balance.Int64())
+	}
+	st2, err := s2.Load("c")
+	require.Nil(t, err)
+	require.NotNil(t, st2)
+}
+
+func TestHandleConfirmation(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	require.Nil(t, a.Reconcile())
+	st, err := a.State()
+	require.Nil(t, err)
+	assert.Len(t, st.Receivable, 1)
+
+	// Another wallet instance publishes a block on the same chain.
+	w2 := newTestWallet(t, n)
+	a2, err := w2.NewAccount(nil)
+	require.Nil(t, err)
+	receiveAll(t, a2)
+	st2, err := a2.State()
+	require.Nil(t, err)
+	n.mu.Lock()
+	info := n.blocks[hex.EncodeToString(st2.Frontier)]
+	n.mu.Unlock()
+	require.Nil(t, w.HandleConfirmation(&websocket.Confirmation{
+		Account: a.Address(),
+		Amount:  info.Amount,
+		Hash:    st2.Frontier,
+		Block:   info.Contents,
+	}))
+	st, err = a.State()
+	require.Nil(t, err)
+	assert.Equal(t, st2.Frontier, st.Frontier)
+	assert.Equal(t, int64(100), st.Balance.Int64())
+	assert.Len(t, st.Receivable, 0)
+}
@@ -0,0 +1,67 @@
+// Package box encrypts messages between Nano accounts. The accounts'
+// Ed25519 keys are converted to X25519 keys, and messages are sealed
+// with NaCl box using the shared key agreed between them. Only the
+// holder of either account key can open a message, so the recipient
+// knows it came from the sender.
+package box
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/hectorchu/gonano/wallet/ed25519"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+const (
+	version   = 1
+	nonceSize = 24
+)
+
+// SharedKey agrees the key shared between the owner of privateKey and
+// the account with the given public key.
+func SharedKey(privateKey ed25519.PrivateKey, pubkey []byte) (key *[32]byte, err error) {
+	u, err := ed25519.PublicKeyToCurve25519(pubkey)
+	if err != nil {
+		return
+	}
+	shared, err := curve25519.X25519(ed25519.PrivateKeyToCurve25519(privateKey), u)
+	if err != nil {
+		return
+	}
+	// Derive the key as box.Precompute does, after X25519 has
+	// rejected low order points.
+	var zero [16]byte
+	var s [32]byte
+	copy(s[:], shared)
+	key = new([32]byte)
+	salsa.HSalsa20(key, &zero, &s, &salsa.Sigma)
+	return
+}
+
+// Seal encrypts and authenticates message with a shared key.
+func Seal(message []byte, key *[32]byte) (sealed []byte, err error) {
+	var nonce [nonceSize]byte
+	if _, err = io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return
+	}
+	sealed = append([]byte{version}, nonce[:]...)
+	return box.SealAfterPrecomputation(sealed, message, &nonce, key), nil
+}
+
+// Open authenticates and decrypts a message sealed with a shared key.
+func Open(sealed []byte, key *[32]byte) (message []byte, err error) {
+	if len(sealed) < 1+nonceSize+box.Overhead || sealed[0] != version {
+		return nil, errors.New("invalid sealed message")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[1:])
+	message, ok := box.OpenAfterPrecomputation(nil, sealed[1+nonceSize:], &nonce, key)
+	if !ok {
+		return nil, errors.New("message could not be opened")
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package box
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hectorchu/gonano/wallet/ed25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestBox(t *testing.T) {
+	alice := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, 32))
+	bob := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, 32))
+	eve := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, 32))
+
+	// The converted public key matches the converted private key.
+	u, err := ed25519.PublicKeyToCurve25519(alice.Public().(ed25519.PublicKey))
+	require.Nil(t, err)
+	u2, err := curve25519.X25519(ed25519.PrivateKeyToCurve25519(alice), curve25519.Basepoint)
+	require.Nil(t, err)
+	assert.Equal(t, u2, u)
+
+	k1, err := SharedKey(alice, bob[32:])
+	require.Nil(t, err)
+	k2, err := SharedKey(bob, alice[32:])
+	require.Nil(t, err)
+	assert.Equal(t, k1, k2)
+	k3, err := SharedKey(eve, alice[32:])
+	require.Nil(t, err)
+
+	sealed, err := Seal([]byte("invoice #1"), k1)
+	require.Nil(t, err)
+	message, err := Open(sealed, k2)
+	require.Nil(t, err)
+	assert.Equal(t, "invoice #1", string(message))
+	_, err = Open(sealed, k3)
+	assert.NotNil(t, err)
+	sealed[len(sealed)-1] ^= 1
+	_, err = Open(sealed, k2)
+	assert.NotNil(t, err)
+
+	_, err = SharedKey(alice, make([]byte, 32))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/websocket"
+)
+
+// HandleConfirmation updates the tracked state of the wallet's accounts
+// from a websocket confirmation.
+func (w *Wallet) HandleConfirmation(c *websocket.Confirmation) (err error) {
+	if c.Block == nil {
+		return
+	}
+	if a := w.GetAccount(c.Account); a != nil {
+		if err = a.confirmed(c.Hash, c.Block); err != nil {
+			return
+		}
+	}
+	if c.Block.Subtype != "send" {
+		return
+	}
+	dest, err := util.PubkeyToAddress(c.Block.Link)
+	if err != nil {
+		return
+	}
+	if a := w.GetAccount(dest); a != nil {
+		err = a.addReceivable(c.Hash, rpc.AccountPending{Amount: c.Amount, Source: c.Account})
+	}
+	return
+}
+
+func (a *Account) loadState() (err error) {
+	if a.st == nil && a.w.Store != nil {
+		a.st, err = a.w.Store.Load(a.address)
+	}
+	return
+}
+
+func (a *Account) confirmed(hash rpc.BlockHash, block *rpc.Block) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err = a.loadState(); err != nil || a.st == nil {
+		return
+	}
+	switch {
+	case bytes.Equal(hash, a.st.Frontier):
+	case bytes.Equal(block.Previous, a.st.Frontier),
+		a.st.Frontier == nil && bytes.Equal(block.Previous, make([]byte, 32)):
+		err = a.advance(hash, block)
+	default:
+		// The chain has moved on without us, so drop the tracked
+		// state and let the next operation reconcile with the node.
+		err = a.setState(nil)
+	}
+	return
+}
+
+func (a *Account) addReceivable(hash rpc.BlockHash, pending rpc.AccountPending) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err = a.loadState(); err != nil || a.st == nil || a.st.Receivable == nil {
+		return
+	}
+	st := a.st.copy()
+	st.Receivable[hex.EncodeToString(hash)] = pending
+	return a.setState(st)
+}
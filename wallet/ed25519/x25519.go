@@ -0,0 +1,45 @@
+package ed25519
+
+import (
+	"errors"
+
+	"github.com/hectorchu/gonano/wallet/ed25519/edwards25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// PublicKeyToCurve25519 converts an Ed25519 public key to the X25519
+// public key (Montgomery u-coordinate) of the same point, u = (1+y)/(1-y).
+func PublicKeyToCurve25519(publicKey PublicKey) (u []byte, err error) {
+	if len(publicKey) != PublicKeySize {
+		return nil, errors.New("ed25519: bad public key length")
+	}
+	var A edwards25519.ExtendedGroupElement
+	var publicKeyBytes [32]byte
+	copy(publicKeyBytes[:], publicKey)
+	if !A.FromBytes(&publicKeyBytes) {
+		return nil, errors.New("ed25519: invalid public key")
+	}
+	var y, one, num, den edwards25519.FieldElement
+	edwards25519.FeFromBytes(&y, &publicKeyBytes)
+	edwards25519.FeOne(&one)
+	edwards25519.FeAdd(&num, &one, &y)
+	edwards25519.FeSub(&den, &one, &y)
+	if edwards25519.FeIsNonZero(&den) == 0 {
+		return nil, errors.New("ed25519: invalid public key")
+	}
+	edwards25519.FeInvert(&den, &den)
+	edwards25519.FeMul(&num, &num, &den)
+	var out [32]byte
+	edwards25519.FeToBytes(&out, &num)
+	return out[:], nil
+}
+
+// PrivateKeyToCurve25519 converts an Ed25519 private key to the X25519
+// private key with the same scalar.
+func PrivateKeyToCurve25519(privateKey PrivateKey) (scalar []byte) {
+	digest := blake2b.Sum512(privateKey.Seed())
+	digest[0] &= 248
+	digest[31] &= 127
+	digest[31] |= 64
+	return digest[:32]
+}
@@ -2,50 +2,112 @@ package wallet
 
 import (
 	"bytes"
+	"sync"
 
 	"github.com/hectorchu/gonano/ledger"
 	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/wallet/box"
 	"github.com/hectorchu/gonano/wallet/ed25519"
 )
 
-type seedImpl struct{}
+// Signer derives account keys and signs blocks on behalf of a wallet.
+// Implementations may hold keys in memory, on a hardware device or
+// behind a remote service.
+type Signer interface {
+	// Pubkey returns the public key of the account at index.
+	Pubkey(index uint32) (pubkey []byte, err error)
+	// SignBlock sets the signature of block using the key at index.
+	SignBlock(index uint32, block *rpc.Block) (err error)
+}
+
+// KeyAgreer is implemented by signers which can agree a key with
+// another account, for encrypting messages between them.
+type KeyAgreer interface {
+	// SharedKey returns the key shared between the account at index and
+	// the account with the given public key.
+	SharedKey(index uint32, pubkey []byte) (key *[32]byte, err error)
+}
 
-func (seedImpl) deriveAccount(a *Account) (err error) {
-	var key []byte
-	if a.w.isBip39 {
-		key, err = deriveBip39Key(a.w.seed, a.index)
+type seedSigner struct {
+	mu      sync.Mutex
+	seed    []byte
+	isBip39 bool
+	keys    map[uint32]ed25519.PrivateKey
+}
+
+func (s *seedSigner) key(index uint32) (key ed25519.PrivateKey, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[index]; ok {
+		return key, nil
+	}
+	if s.isBip39 {
+		key, err = deriveBip39Key(s.seed, index)
 	} else {
-		key, err = deriveKey(a.w.seed, a.index)
+		key, err = deriveKey(s.seed, index)
 	}
 	if err != nil {
 		return
 	}
-	a.pubkey, a.key, err = deriveKeypair(key)
+	if _, key, err = deriveKeypair(key); err != nil {
+		return
+	}
+	if s.keys == nil {
+		s.keys = make(map[uint32]ed25519.PrivateKey)
+	}
+	s.keys[index] = key
 	return
 }
 
-func (seedImpl) signBlock(a *Account, block *rpc.Block) (err error) {
+func (s *seedSigner) Pubkey(index uint32) (pubkey []byte, err error) {
+	key, err := s.key(index)
+	if err != nil {
+		return
+	}
+	return key[32:], nil
+}
+
+func (s *seedSigner) SignBlock(index uint32, block *rpc.Block) (err error) {
+	key, err := s.key(index)
+	if err != nil {
+		return
+	}
 	hash, err := block.Hash()
 	if err != nil {
 		return
 	}
-	block.Signature = ed25519.Sign(a.key, hash)
+	block.Signature = ed25519.Sign(key, hash)
 	return
 }
 
-type ledgerImpl struct{}
+func (s *seedSigner) SharedKey(index uint32, pubkey []byte) (key *[32]byte, err error) {
+	k, err := s.key(index)
+	if err != nil {
+		return
+	}
+	return box.SharedKey(k, pubkey)
+}
+
+type ledgerSigner struct {
+	mu sync.Mutex
+	w  *Wallet
+}
 
-func (ledgerImpl) deriveAccount(a *Account) (err error) {
-	path := []uint32{44, 165, a.index}
-	a.pubkey, _, err = ledger.GetAddress(path)
+func (s *ledgerSigner) Pubkey(index uint32) (pubkey []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := []uint32{44, 165, index}
+	pubkey, _, err = ledger.GetAddress(path)
 	return
 }
 
-func (ledgerImpl) signBlock(a *Account, block *rpc.Block) (err error) {
-	path := []uint32{44, 165, a.index}
+func (s *ledgerSigner) SignBlock(index uint32, block *rpc.Block) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := []uint32{44, 165, index}
 	var zero [32]byte
 	if !bytes.Equal(block.Previous, zero[:]) {
-		bi, err := a.w.RPC.BlockInfo(block.Previous)
+		bi, err := s.w.RPC.BlockInfo(block.Previous)
 		if err != nil {
 			return err
 		}
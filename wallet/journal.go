@@ -0,0 +1,181 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+)
+
+// Journal states.
+const (
+	// JournalSigned means the block was signed but it is not known
+	// whether it reached the network.
+	JournalSigned = "signed"
+	// JournalPublished means the block was accepted by the node.
+	JournalPublished = "published"
+	// JournalFailed means the block was rejected and can be retried.
+	JournalFailed = "failed"
+)
+
+// JournalEntry records a payment so that its outcome can be recovered
+// after a crash.
+type JournalEntry struct {
+	ID          string         `json:"id"`
+	Account     string         `json:"account"`
+	Destination string         `json:"destination"`
+	Amount      *rpc.RawAmount `json:"amount"`
+	Block       *rpc.Block     `json:"block"`
+	Hash        rpc.BlockHash  `json:"hash"`
+	State       string         `json:"state"`
+	Error       string         `json:"error,omitempty"`
+	Time        time.Time      `json:"time"`
+}
+
+// Journal durably records payments before they are published.
+type Journal interface {
+	// Get returns the latest entry with id, or nil if there is none.
+	Get(id string) (e *JournalEntry, err error)
+	// Put durably records e, replacing any entry with the same id.
+	Put(e *JournalEntry) (err error)
+	// Unresolved returns the entries in the signed state.
+	Unresolved() (entries []*JournalEntry, err error)
+}
+
+// FileJournal is a Journal backed by an append-only file of JSON lines.
+type FileJournal struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]*JournalEntry
+}
+
+// OpenFileJournal opens the journal at path, creating it if necessary.
+func OpenFileJournal(path string) (j *FileJournal, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	j = &FileJournal{f: f, entries: make(map[string]*JournalEntry)}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e JournalEntry
+		// A crash may leave a partially written last line.
+		if json.Unmarshal(s.Bytes(), &e) == nil {
+			j.entries[e.ID] = &e
+		}
+	}
+	if err = s.Err(); err != nil {
+		f.Close()
+	}
+	return
+}
+
+// Close closes the journal file.
+func (j *FileJournal) Close() error {
+	return j.f.Close()
+}
+
+// Get returns the latest entry with id, or nil if there is none.
+func (j *FileJournal) Get(id string) (e *JournalEntry, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if e = j.entries[id]; e != nil {
+		e2 := *e
+		e = &e2
+	}
+	return
+}
+
+// Put durably records e, replacing any entry with the same id.
+func (j *FileJournal) Put(e *JournalEntry) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// Start on a fresh line in case the last write was torn.
+	if _, err = j.f.Write(append(append([]byte{'\n'}, data...), '\n')); err != nil {
+		return
+	}
+	if err = j.f.Sync(); err != nil {
+		return
+	}
+	e2 := *e
+	j.entries[e.ID] = &e2
+	return
+}
+
+// Unresolved returns the entries in the signed state.
+func (j *FileJournal) Unresolved() (entries []*JournalEntry, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, e := range j.entries {
+		if e.State == JournalSigned {
+			e2 := *e
+			entries = append(entries, &e2)
+		}
+	}
+	return
+}
+
+// ReconcileJournal resolves journal entries whose outcome is unknown by
+// looking up their blocks on the node. A block that is not found is
+// republished if it still extends the account's chain, otherwise it is
+// marked as failed. The updated entries are returned.
+func (w *Wallet) ReconcileJournal() (entries []*JournalEntry, err error) {
+	if w.Journal == nil {
+		return
+	}
+	if entries, err = w.Journal.Unresolved(); err != nil {
+		return
+	}
+	for _, e := range entries {
+		if err = w.reconcileEntry(e); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (w *Wallet) reconcileEntry(e *JournalEntry) (err error) {
+	if _, err = w.RPC.BlockInfo(e.Hash); err == nil {
+		e.State = JournalPublished
+	} else if err.Error() != "Block not found" {
+		return
+	} else {
+		var frontier rpc.BlockHash
+		info, err := w.RPC.AccountInfo(e.Account)
+		if err == nil {
+			frontier = info.Frontier
+		} else if err.Error() != "Account not found" {
+			return err
+		} else {
+			frontier = make(rpc.BlockHash, 32)
+		}
+		e.State = JournalFailed
+		if bytes.Equal(frontier, e.Block.Previous) {
+			if _, err = w.RPC.Process(e.Block, "send"); err == nil {
+				e.State = JournalPublished
+			} else if !errors.As(err, new(rpc.Error)) {
+				return err
+			}
+		}
+		if err != nil {
+			e.Error = err.Error()
+		}
+	}
+	if a := w.GetAccount(e.Account); a != nil {
+		a.mu.Lock()
+		a.setState(nil)
+		a.mu.Unlock()
+	}
+	e.Time = time.Now().UTC()
+	return w.Journal.Put(e)
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJournal(t *testing.T) (j *FileJournal, path string) {
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path = filepath.Join(dir, "journal.jsonl")
+	j, err = OpenFileJournal(path)
+	require.Nil(t, err)
+	t.Cleanup(func() { j.Close() })
+	return
+}
+
+func TestPayIdempotent(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	w.Journal, _ = newTestJournal(t)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	receiveAll(t, a)
+	batch := &Batch{
+		Sources:  []*Account{a},
+		Payments: []Payment{{ID: "x", Account: a.Address(), Amount: big.NewInt(10)}},
+	}
+	r1 := w.Pay(batch)
+	require.Nil(t, r1[0].Err)
+	r2 := w.Pay(batch)
+	require.Nil(t, r2[0].Err)
+	assert.Equal(t, r1[0].Hash, r2[0].Hash)
+	balance, _, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(90), balance.Int64())
+	e, err := w.Journal.Get("x")
+	require.Nil(t, err)
+	assert.Equal(t, JournalPublished, e.State)
+	batch.Payments[0].Amount = big.NewInt(20)
+	r3 := w.Pay(batch)
+	assert.EqualError(t, r3[0].Err, "id reused with different payment")
+	balance, _, err = a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(90), balance.Int64())
+}
+
+func TestPayDropped(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	w.Journal, _ = newTestJournal(t)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	receiveAll(t, a)
+	batch := &Batch{
+		Sources:  []*Account{a},
+		Payments: []Payment{{ID: "x", Account: a.Address(), Amount: big.NewInt(10)}},
+	}
+	n.drop = 1
+	r1 := w.Pay(batch)
+	require.NotNil(t, r1[0].Err)
+	e, err := w.Journal.Get("x")
+	require.Nil(t, err)
+	assert.Equal(t, JournalSigned, e.State)
+	r2 := w.Pay(batch)
+	require.Nil(t, r2[0].Err)
+	assert.Equal(t, e.Hash, r2[0].Hash)
+	balance, _, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(90), balance.Int64())
+}
+
+func TestReconcileJournal(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	j, path := newTestJournal(t)
+	w.Journal = j
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	receiveAll(t, a)
+
+	// Simulate a crash after journaling two signed blocks, neither of
+	// which was published. Only the first still extends the chain.
+	var hashes []string
+	for i, id := range []string{"a", "b"} {
+		block, err := a.SendBlock(a.Address(), big.NewInt(10))
+		require.Nil(t, err)
+		if i > 0 {
+			block.Previous = bytes.Repeat([]byte{9}, 32)
+		}
+		hash, _ := block.Hash()
+		hashes = append(hashes, hash.String())
+		require.Nil(t, j.Put(&JournalEntry{
+			ID: id, Account: a.Address(), Block: block, Hash: hash, State: JournalSigned,
+		}))
+	}
+	j.Close()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.Nil(t, err)
+	f.WriteString(`{"id":"c","sta`)
+	f.Close()
+
+	w.Journal, err = OpenFileJournal(path)
+	require.Nil(t, err)
+	entries, err := w.Journal.Unresolved()
+	require.Nil(t, err)
+	assert.Len(t, entries, 2)
+	_, err = w.ReconcileJournal()
+	require.Nil(t, err)
+	e, err := w.Journal.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, JournalPublished, e.State)
+	assert.Equal(t, hashes[0], e.Hash.String())
+	e, err = w.Journal.Get("b")
+	require.Nil(t, err)
+	assert.Equal(t, JournalFailed, e.State)
+	balance, _, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(90), balance.Int64())
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet/box"
+	"github.com/hectorchu/gonano/wallet/ed25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// messageBlock returns the dummy block signed in place of message, as
+// used by other Nano wallets: an open block for address with zero
+// balance and link, whose representative is the message's hash taken
+// as a public key. Signing a block means any signer, including a
+// Ledger, can sign messages, and the signature can't be replayed as a
+// real block since the account can't open with nothing to receive.
+func messageBlock(address string, message []byte) (block *rpc.Block, err error) {
+	hash := blake2b.Sum256(message)
+	rep, err := util.PubkeyToAddress(hash[:])
+	if err != nil {
+		return
+	}
+	block = &rpc.Block{
+		Type:           "state",
+		Account:        address,
+		Previous:       make(rpc.BlockHash, 32),
+		Representative: rep,
+		Balance:        &rpc.RawAmount{},
+		Link:           make(rpc.BlockHash, 32),
+	}
+	return
+}
+
+// SignMessage signs message with the account's key, proving ownership
+// of the account.
+func (a *Account) SignMessage(message []byte) (signature []byte, err error) {
+	block, err := messageBlock(a.address, message)
+	if err != nil {
+		return
+	}
+	if err = a.w.signer.SignBlock(a.index, block); err != nil {
+		return
+	}
+	return block.Signature, nil
+}
+
+// VerifyMessage reports whether signature is a valid signature of
+// message by the owner of address.
+func VerifyMessage(address string, message, signature []byte) (valid bool, err error) {
+	pubkey, err := util.AddressToPubkey(address)
+	if err != nil {
+		return
+	}
+	block, err := messageBlock(address, message)
+	if err != nil {
+		return
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return
+	}
+	return ed25519.Verify(pubkey, hash, signature), nil
+}
+
+// ErrNoKeyAgreement is returned when encrypting or decrypting with a
+// wallet whose signer doesn't implement KeyAgreer.
+var ErrNoKeyAgreement = errors.New("wallet cannot encrypt messages")
+
+func (a *Account) sharedKey(address string) (key *[32]byte, err error) {
+	ka, ok := a.w.signer.(KeyAgreer)
+	if !ok {
+		return nil, ErrNoKeyAgreement
+	}
+	pubkey, err := util.AddressToPubkey(address)
+	if err != nil {
+		return
+	}
+	return ka.SharedKey(a.index, pubkey)
+}
+
+// Encrypt encrypts message so that only the owner of address can
+// decrypt it, and know that it came from this account.
+func (a *Account) Encrypt(message []byte, address string) (sealed []byte, err error) {
+	key, err := a.sharedKey(address)
+	if err != nil {
+		return
+	}
+	return box.Seal(message, key)
+}
+
+// Decrypt decrypts a message encrypted by the owner of address to this
+// account, failing if it was not.
+func (a *Account) Decrypt(sealed []byte, address string) (message []byte, err error) {
+	key, err := a.sharedKey(address)
+	if err != nil {
+		return
+	}
+	return box.Open(sealed, key)
+}
@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignMessage(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	message := []byte("I own this account")
+	sig, err := a.SignMessage(message)
+	require.Nil(t, err)
+	valid, err := VerifyMessage(a.Address(), message, sig)
+	require.Nil(t, err)
+	assert.True(t, valid)
+	valid, err = VerifyMessage(a.Address(), []byte("I own this account!"), sig)
+	require.Nil(t, err)
+	assert.False(t, valid)
+	valid, err = VerifyMessage(b.Address(), message, sig)
+	require.Nil(t, err)
+	assert.False(t, valid)
+	_, err = VerifyMessage("nano_invalid", message, sig)
+	assert.NotNil(t, err)
+}
+
+func TestEncrypt(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	sealed, err := a.Encrypt([]byte("memo"), b.Address())
+	require.Nil(t, err)
+	message, err := b.Decrypt(sealed, a.Address())
+	require.Nil(t, err)
+	assert.Equal(t, "memo", string(message))
+	_, err = a.Decrypt(sealed, a.Address())
+	assert.NotNil(t, err)
+}
+
+// TestSignMessageVector checks a signature computed by an independent
+// implementation of Ed25519 with Blake2b, signing the dummy open block
+// whose representative is blake2b-256 of the message.
+func TestSignMessageVector(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	require.Equal(t, "nano_1sjkhzzeuhup4u9fbd9f77k9puwfbaadymfjnjgbtmiuchqqnmodbwrsnhn9", a.Address())
+	message := []byte("I own this account")
+	block, err := messageBlock(a.Address(), message)
+	require.Nil(t, err)
+	assert.Equal(t, "nano_3w6gtfd1ywnh8iergp7nksqtpn3bc6khuszxrarnief78rqnjp93g8anymdt", block.Representative)
+	hash, err := block.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, "8637BFDA671DF7087CEE25BFE5AE2D9248A2B1F644DD1B64BB65565B0A8C2D2E", hash.String())
+	sig, err := a.SignMessage(message)
+	require.Nil(t, err)
+	assert.Equal(t, "C90413BB7D21DD87E15CACF53AD6F62C29FBA680E115F07D8461C12FB3038B6C"+
+		"8533A36418E39BE8A8903915CE0BFF1D9FA460F9E1400E6253B06215F9CB0C06", strings.ToUpper(hex.EncodeToString(sig)))
+}
@@ -0,0 +1,250 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is a minimal in-memory node implementing the RPCs used by the wallet.
+type fakeNode struct {
+	*httptest.Server
+	mu       sync.Mutex
+	accounts map[string]*rpc.AccountInfo
+	blocks   map[string]*rpc.BlockInfo
+	pending  map[string]rpc.HashToPendingMap
+	calls    map[string]int
+	reps     map[string]rpc.Representative
+	clock    uint64
+	// reject, if set, causes receives of that link to fail.
+	reject rpc.BlockHash
+	// drop is the number of process calls still to be dropped without
+	// a response from the node, as if the connection failed.
+	drop int
+}
+
+func newFakeNode() *fakeNode {
+	n := &fakeNode{
+		accounts: make(map[string]*rpc.AccountInfo),
+		blocks:   make(map[string]*rpc.BlockInfo),
+		pending:  make(map[string]rpc.HashToPendingMap),
+		calls:    make(map[string]int),
+	}
+	n.Server = httptest.NewServer(n)
+	return n
+}
+
+func newTestWallet(t *testing.T, n *fakeNode) *Wallet {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	w.RPC.URL = n.URL
+	w.RPCWork.URL = n.URL
+	return w
+}
+
+// receiveAll receives all pending blocks to a, failing the test on any error.
+func receiveAll(t *testing.T, a *Account) {
+	results, err := a.ReceivePendings()
+	require.Nil(t, err)
+	for _, r := range results {
+		require.Nil(t, r.Err)
+	}
+}
+
+// fund adds a pending amount from a made-up source to account.
+func (n *fakeNode) fund(account string, amount int64, hash byte) rpc.BlockHash {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	link := bytes.Repeat([]byte{hash}, 32)
+	if n.pending[account] == nil {
+		n.pending[account] = make(rpc.HashToPendingMap)
+	}
+	n.pending[account][hex.EncodeToString(link)] = rpc.AccountPending{
+		Amount: &rpc.RawAmount{Int: *big.NewInt(amount)},
+		Source: account,
+	}
+	n.blocks[hex.EncodeToString(link)] = &rpc.BlockInfo{
+		BlockAccount:   account,
+		Amount:         &rpc.RawAmount{Int: *big.NewInt(amount)},
+		Balance:        &rpc.RawAmount{},
+		LocalTimestamp: n.tick(),
+		Subtype:        "send",
+	}
+	return link
+}
+
+func (n *fakeNode) tick() uint64 {
+	n.clock++
+	return n.clock
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action   string
+		Account  string
+		Accounts []string
+		Hash     rpc.BlockHash
+		Hashes   []rpc.BlockHash
+		Block    *rpc.Block
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[req.Action]++
+	var resp interface{}
+	switch req.Action {
+	case "account_info":
+		if info, ok := n.accounts[req.Account]; ok {
+			resp = info
+		} else {
+			resp = map[string]string{"error": "Account not found"}
+		}
+	case "account_balance":
+		resp = n.balance(req.Account)
+	case "accounts_balances":
+		balances := make(map[string]*rpc.AccountBalance)
+		for _, account := range req.Accounts {
+			balances[account] = n.balance(account)
+		}
+		resp = map[string]interface{}{"balances": balances}
+	case "accounts_frontiers":
+		frontiers := make(map[string]rpc.BlockHash)
+		for _, account := range req.Accounts {
+			if info, ok := n.accounts[account]; ok {
+				frontiers[account] = info.Frontier
+			}
+		}
+		resp = map[string]interface{}{"frontiers": frontiers}
+	case "accounts_pending":
+		blocks := make(map[string]rpc.HashToPendingMap)
+		for _, account := range req.Accounts {
+			if len(n.pending[account]) > 0 {
+				blocks[account] = n.pending[account]
+			}
+		}
+		resp = map[string]interface{}{"blocks": blocks}
+	case "block_info":
+		if info, ok := n.blocks[hex.EncodeToString(req.Hash)]; ok {
+			resp = info
+		} else {
+			resp = map[string]string{"error": "Block not found"}
+		}
+	case "blocks_info":
+		blocks := make(map[string]*rpc.BlockInfo)
+		for _, hash := range req.Hashes {
+			info, ok := n.blocks[hex.EncodeToString(hash)]
+			if !ok {
+				blocks = nil
+				break
+			}
+			blocks[hash.String()] = info
+		}
+		if blocks != nil {
+			resp = map[string]interface{}{"blocks": blocks}
+		} else {
+			resp = map[string]string{"error": "Block not found"}
+		}
+	case "representatives_online":
+		resp = map[string]interface{}{"representatives": n.reps}
+	case "work_generate":
+		resp = map[string]string{"work": "0000000000000000", "difficulty": "0000000000000000", "multiplier": "1"}
+	case "process":
+		if n.drop > 0 {
+			n.drop--
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		hash, err := n.process(req.Block)
+		if err != nil {
+			resp = map[string]string{"error": err.Error()}
+		} else {
+			resp = map[string]interface{}{"hash": hash}
+		}
+	default:
+		resp = map[string]string{"error": "Unknown command"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (n *fakeNode) balance(account string) *rpc.AccountBalance {
+	b := &rpc.AccountBalance{Balance: &rpc.RawAmount{}, Pending: &rpc.RawAmount{}}
+	if info, ok := n.accounts[account]; ok {
+		b.Balance.Set(&info.Balance.Int)
+	}
+	for _, p := range n.pending[account] {
+		b.Pending.Add(&b.Pending.Int, &p.Amount.Int)
+	}
+	return b
+}
+
+type nodeError string
+
+func (e nodeError) Error() string { return string(e) }
+
+func (n *fakeNode) process(block *rpc.Block) (hash rpc.BlockHash, err error) {
+	if hash, err = block.Hash(); err != nil {
+		return
+	}
+	if _, ok := n.blocks[hex.EncodeToString(hash)]; ok {
+		return nil, nodeError("Old block")
+	}
+	info, ok := n.accounts[block.Account]
+	if !ok {
+		if !bytes.Equal(block.Previous, make([]byte, 32)) {
+			return nil, nodeError("Gap previous block")
+		}
+		info = &rpc.AccountInfo{Balance: &rpc.RawAmount{}}
+	} else if !bytes.Equal(block.Previous, info.Frontier) {
+		return nil, nodeError("Fork")
+	}
+	var amount big.Int
+	subtype := "change"
+	switch amount.Sub(&block.Balance.Int, &info.Balance.Int).Sign() {
+	case 1:
+		subtype = "receive"
+		link := hex.EncodeToString(block.Link)
+		p, ok := n.pending[block.Account][link]
+		if !ok || p.Amount.Cmp(&amount) != 0 || bytes.Equal(block.Link, n.reject) {
+			return nil, nodeError("Unreceivable")
+		}
+		delete(n.pending[block.Account], link)
+	case -1:
+		subtype = "send"
+		amount.Neg(&amount)
+		dest, _ := util.PubkeyToAddress(block.Link)
+		if n.pending[dest] == nil {
+			n.pending[dest] = make(rpc.HashToPendingMap)
+		}
+		n.pending[dest][hex.EncodeToString(hash)] = rpc.AccountPending{
+			Amount: &rpc.RawAmount{Int: amount},
+			Source: block.Account,
+		}
+	}
+	n.accounts[block.Account] = &rpc.AccountInfo{
+		Frontier:       hash,
+		Balance:        block.Balance,
+		BlockCount:     info.BlockCount + 1,
+		Representative: block.Representative,
+	}
+	n.blocks[hex.EncodeToString(hash)] = &rpc.BlockInfo{
+		BlockAccount:   block.Account,
+		Amount:         &rpc.RawAmount{Int: amount},
+		Balance:        block.Balance,
+		Height:         info.BlockCount + 1,
+		Confirmed:      true,
+		LocalTimestamp: n.tick(),
+		Contents:       block,
+		Subtype:        subtype,
+	}
+	return
+}
@@ -0,0 +1,319 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+)
+
+// Payment is a single payment within a batch. If a Journal is in use, a
+// payment with an ID is never made twice, even across runs.
+type Payment struct {
+	ID      string
+	Account string
+	Amount  *big.Int
+}
+
+// PaymentResult reports the outcome of a payment.
+type PaymentResult struct {
+	Payment
+	Source string
+	Hash   rpc.BlockHash
+	Err    error
+}
+
+// Batch is a list of payments to be made from one or more source accounts.
+type Batch struct {
+	Sources  []*Account
+	Payments []Payment
+	// Done, if set, is called with the index and result of each payment
+	// as soon as its outcome is known, so that progress survives a crash
+	// part way through the batch. Calls are not made concurrently.
+	Done func(i int, r PaymentResult)
+}
+
+const payWorkers = 4
+
+type payment struct {
+	index  int
+	result *PaymentResult
+	block  *rpc.Block
+	work   chan error
+}
+
+// Pay makes the payments in batch. Payments are spread over the source
+// accounts in turn, skipping accounts with insufficient funds. The send
+// blocks for each source are chained locally and their work generated
+// concurrently, then published in order. A result is returned for each
+// payment, in the same order as the batch.
+func (w *Wallet) Pay(batch *Batch) (results []PaymentResult) {
+	results = make([]PaymentResult, len(batch.Payments))
+	var doneMu sync.Mutex
+	finish := func(i int) {
+		if batch.Done != nil {
+			doneMu.Lock()
+			defer doneMu.Unlock()
+			batch.Done(i, results[i])
+		}
+	}
+	done := w.resolvePayments(batch.Payments, results)
+	for i := range done {
+		if done[i] {
+			finish(i)
+		}
+	}
+	var sources []*Account
+	seen := make(map[string]bool)
+	for _, a := range batch.Sources {
+		if !seen[a.address] {
+			seen[a.address] = true
+			sources = append(sources, a)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].address < sources[j].address })
+	for _, a := range sources {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+	}
+	states := make([]*AccountState, len(sources))
+	stateErrs := make([]error, len(sources))
+	for i, a := range sources {
+		st, err := a.state()
+		if err == nil && a.representative == "" {
+			a.representative = st.Representative
+		}
+		states[i], stateErrs[i] = st, err
+	}
+	chains := make([][]*payment, len(sources))
+	next := 0
+	for i, p := range batch.Payments {
+		if done[i] {
+			continue
+		}
+		r := &results[i]
+		link, err := util.AddressToPubkey(p.Account)
+		if err != nil {
+			r.Err = err
+			finish(i)
+			continue
+		}
+		if p.Amount == nil || p.Amount.Sign() <= 0 {
+			r.Err = errors.New("invalid amount")
+			finish(i)
+			continue
+		}
+		// A payment which no source could fund is failed with the error
+		// of a source whose state is unknown, as it may have had the funds.
+		r.Err = ErrInsufficientFunds
+		for j := range sources {
+			k := (next + j) % len(sources)
+			a, st := sources[k], states[k]
+			if stateErrs[k] != nil {
+				if r.Err == ErrInsufficientFunds {
+					r.Err = stateErrs[k]
+				}
+				continue
+			}
+			if st.Frontier == nil || st.Balance.Cmp(p.Amount) < 0 {
+				continue
+			}
+			block := &rpc.Block{
+				Type:           "state",
+				Account:        a.address,
+				Previous:       st.Frontier,
+				Representative: a.representative,
+				Balance:        &rpc.RawAmount{},
+				Link:           link,
+			}
+			block.Balance.Sub(&st.Balance.Int, p.Amount)
+			hash, err := block.Hash()
+			if err != nil {
+				r.Err = err
+				break
+			}
+			st.Frontier = hash
+			st.Balance.Set(&block.Balance.Int)
+			r.Source, r.Err = a.address, nil
+			chains[k] = append(chains[k], &payment{index: i, result: r, block: block, work: make(chan error, 1)})
+			next = k + 1
+			break
+		}
+		if r.Source == "" {
+			finish(i)
+		}
+	}
+	w.payWork(chains)
+	var wg sync.WaitGroup
+	for i, a := range sources {
+		wg.Add(1)
+		go func(a *Account, chain []*payment) {
+			defer wg.Done()
+			a.publish(chain, finish)
+		}(a, chains[i])
+	}
+	wg.Wait()
+	return
+}
+
+// resolvePayments fills in the results of payments which must not be
+// made, either because their ID was already paid or is in doubt, or
+// because it is repeated within the batch.
+func (w *Wallet) resolvePayments(payments []Payment, results []PaymentResult) (done []bool) {
+	done = make([]bool, len(payments))
+	seen := make(map[string]bool)
+	for i, p := range payments {
+		r := &results[i]
+		r.Payment = p
+		if p.ID == "" {
+			continue
+		}
+		if done[i] = seen[p.ID]; done[i] {
+			r.Err = errors.New("duplicate payment id")
+			continue
+		}
+		seen[p.ID] = true
+		if w.Journal == nil {
+			continue
+		}
+		e, err := w.Journal.Get(p.ID)
+		if err == nil && e != nil && e.State != JournalFailed && !e.matches(&p) {
+			err = errors.New("id reused with different payment")
+		}
+		if err == nil && e != nil && e.State == JournalSigned {
+			err = w.reconcileEntry(e)
+		}
+		switch {
+		case err != nil:
+			r.Err, done[i] = err, true
+		case e == nil || e.State == JournalFailed:
+		case e.State == JournalPublished:
+			r.Source, r.Hash, done[i] = e.Account, e.Hash, true
+		default:
+			r.Err, done[i] = errors.New("payment outcome unknown"), true
+		}
+	}
+	return
+}
+
+// matches reports whether the entry is for the same destination and
+// amount as p.
+func (e *JournalEntry) matches(p *Payment) bool {
+	dest, err := util.AddressToPubkey(e.Destination)
+	if err != nil {
+		return false
+	}
+	dest2, err := util.AddressToPubkey(p.Account)
+	if err != nil || !bytes.Equal(dest, dest2) {
+		return false
+	}
+	return e.Amount != nil && p.Amount != nil && e.Amount.Cmp(p.Amount) == 0
+}
+
+// payWork generates work for every block in chains using a pool of workers.
+func (w *Wallet) payWork(chains [][]*payment) {
+	queue := make(chan *payment)
+	for i := 0; i < payWorkers; i++ {
+		go func() {
+			for p := range queue {
+				var err error
+				p.block.Work, err = w.workGenerate(p.block.Previous)
+				p.work <- err
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for i := 0; ; i++ {
+			done := true
+			for _, chain := range chains {
+				if i < len(chain) {
+					queue <- chain[i]
+					done = false
+				}
+			}
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+// publish signs and publishes a chain of send blocks in order, calling
+// finish with the index of each payment once its outcome is known. Once a
+// block fails, the rest of the chain cannot be published.
+func (a *Account) publish(chain []*payment, finish func(i int)) {
+	for i, p := range chain {
+		err := a.w.signer.SignBlock(a.index, p.block)
+		if err2 := <-p.work; err == nil {
+			err = err2
+		}
+		if err == nil {
+			p.result.Hash, err = a.publishSend(&p.result.Payment, p.block)
+		}
+		if err != nil {
+			p.result.Err = err
+			finish(p.index)
+			for _, p := range chain[i+1:] {
+				<-p.work
+				p.result.Err = errors.New("previous payment failed")
+				finish(p.index)
+			}
+			return
+		}
+		finish(p.index)
+	}
+}
+
+// publishSend journals a signed send block before publishing it, and
+// records the outcome afterwards. If the outcome cannot be determined the
+// entry is left to be resolved by ReconcileJournal.
+func (a *Account) publishSend(p *Payment, block *rpc.Block) (hash rpc.BlockHash, err error) {
+	if a.w.Journal == nil {
+		return a.process(block, "send")
+	}
+	if hash, err = block.Hash(); err != nil {
+		return
+	}
+	e := &JournalEntry{
+		ID:          p.ID,
+		Account:     a.address,
+		Destination: p.Account,
+		Amount:      &rpc.RawAmount{},
+		Block:       block,
+		Hash:        hash,
+		State:       JournalSigned,
+		Time:        time.Now().UTC(),
+	}
+	if e.ID == "" {
+		e.ID = hash.String()
+	}
+	e.Amount.Set(p.Amount)
+	if err = a.w.Journal.Put(e); err != nil {
+		return nil, err
+	}
+	if _, err = a.process(block, "send"); err == nil {
+		e.State = JournalPublished
+	} else if _, err2 := a.w.RPC.BlockInfo(hash); err2 == nil {
+		e.State, err = JournalPublished, nil
+	} else if errors.As(err, new(rpc.Error)) && err2.Error() == "Block not found" {
+		e.State, e.Error = JournalFailed, err.Error()
+	} else {
+		// The block may yet be published, so the entry is left signed
+		// for a retry to publish the same block.
+		return nil, err
+	}
+	e.Time = time.Now().UTC()
+	if err2 := a.w.Journal.Put(e); err == nil {
+		err = err2
+	}
+	if err != nil {
+		hash = nil
+	}
+	return
+}
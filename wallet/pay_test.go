@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPay(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	var sources []*Account
+	for i := byte(1); i <= 2; i++ {
+		a, err := w.NewAccount(nil)
+		require.Nil(t, err)
+		n.fund(a.Address(), 30, i)
+		receiveAll(t, a)
+		sources = append(sources, a)
+	}
+	dest, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	payments := []Payment{
+		{Account: dest.Address(), Amount: big.NewInt(10)},
+		{Account: dest.Address(), Amount: big.NewInt(10)},
+		{Account: "nano_invalid", Amount: big.NewInt(10)},
+		{Account: dest.Address(), Amount: big.NewInt(25)},
+		{Account: dest.Address(), Amount: big.NewInt(10)},
+		{Account: dest.Address(), Amount: big.NewInt(10)},
+	}
+	done := make(map[int]PaymentResult)
+	results := w.Pay(&Batch{Sources: sources, Payments: payments, Done: func(i int, r PaymentResult) {
+		_, ok := done[i]
+		assert.False(t, ok)
+		done[i] = r
+	}})
+	require.Len(t, results, len(payments))
+	require.Len(t, done, len(payments))
+	for i, r := range results {
+		assert.Equal(t, r, done[i])
+	}
+	assert.Equal(t, sources[0].Address(), results[0].Source)
+	assert.Equal(t, sources[1].Address(), results[1].Source)
+	assert.NotNil(t, results[2].Err)
+	assert.EqualError(t, results[3].Err, "insufficient funds")
+	for _, i := range []int{0, 1, 4, 5} {
+		assert.Nil(t, results[i].Err)
+		assert.NotNil(t, results[i].Hash)
+	}
+	_, pending, err := dest.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(40), pending.Int64())
+	for _, a := range sources {
+		balance, _, err := a.Balance()
+		require.Nil(t, err)
+		assert.Equal(t, int64(10), balance.Int64())
+	}
+}
+
+// failingStore fails to load the state of one account.
+type failingStore struct {
+	Store
+	fail string
+}
+
+func (s *failingStore) Load(account string) (*AccountState, error) {
+	if account == s.fail {
+		return nil, errors.New("store unavailable")
+	}
+	return s.Store.Load(account)
+}
+
+func TestPayStateError(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	for i := byte(1); i <= 2; i++ {
+		a, err := w.NewAccount(nil)
+		require.Nil(t, err)
+		n.fund(a.Address(), 30, i)
+		receiveAll(t, a)
+	}
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(filepath.Join(dir, "state.json"))
+	require.Nil(t, err)
+	w = newTestWallet(t, n)
+	var sources []*Account
+	for i := 0; i < 2; i++ {
+		a, err := w.NewAccount(nil)
+		require.Nil(t, err)
+		sources = append(sources, a)
+	}
+	w.Store = &failingStore{Store: store, fail: sources[1].Address()}
+	dest := "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	results := w.Pay(&Batch{
+		// A source given twice must not deadlock.
+		Sources: []*Account{sources[0], sources[1], sources[0]},
+		Payments: []Payment{
+			{Account: dest, Amount: big.NewInt(20)},
+			{Account: dest, Amount: big.NewInt(20)},
+		},
+	})
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, sources[0].Address(), results[0].Source)
+	assert.EqualError(t, results[1].Err, "store unavailable")
+}
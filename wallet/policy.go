@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/hectorchu/gonano/rpc"
+)
+
+// ReceiveOrder determines the order in which pending blocks are received.
+type ReceiveOrder int
+
+const (
+	// ReceiveOldestFirst receives pending blocks in the order the node
+	// first saw them.
+	ReceiveOldestFirst ReceiveOrder = iota
+	// ReceiveLargestFirst receives the largest amounts first.
+	ReceiveLargestFirst
+	// ReceiveSmallestFirst receives the smallest amounts first.
+	ReceiveSmallestFirst
+)
+
+// ReceivePolicy determines which pending blocks are received, so that
+// proof-of-work is not wasted on spam. The zero policy receives everything.
+type ReceivePolicy struct {
+	// MinAmount is the smallest amount in raws that will be received.
+	MinAmount *big.Int
+	// Allow, if non-empty, lists the only senders to receive from.
+	Allow []string
+	// Deny lists senders never to receive from.
+	Deny []string
+	// Order is the order in which pending blocks are received.
+	Order ReceiveOrder
+	// MaxBlocks, if positive, limits the number of blocks received at once.
+	MaxBlocks int
+}
+
+type pendingBlock struct {
+	link      rpc.BlockHash
+	pending   rpc.AccountPending
+	timestamp uint64
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts reports whether a pending amount from sender should be received.
+func (p *ReceivePolicy) Accepts(sender string, amount *big.Int) bool {
+	if p.MinAmount != nil && amount.Cmp(p.MinAmount) < 0 {
+		return false
+	}
+	if len(p.Allow) > 0 && !containsString(p.Allow, sender) {
+		return false
+	}
+	return !containsString(p.Deny, sender)
+}
+
+// filter returns the accepted pending blocks, in no particular order.
+func (p *ReceivePolicy) filter(pendings rpc.HashToPendingMap) (blocks []pendingBlock, err error) {
+	for hash, pending := range pendings {
+		if !p.Accepts(pending.Source, &pending.Amount.Int) {
+			continue
+		}
+		link, err := hex.DecodeString(hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, pendingBlock{link: link, pending: pending})
+	}
+	return
+}
+
+// sort orders blocks for receiving. Ties are broken by hash so that
+// the order is always the same.
+func (p *ReceivePolicy) sort(blocks []pendingBlock) {
+	sort.Slice(blocks, func(i, j int) bool {
+		a, b := blocks[i], blocks[j]
+		var c int
+		switch p.Order {
+		case ReceiveOldestFirst:
+			if a.timestamp < b.timestamp {
+				c = -1
+			} else if a.timestamp > b.timestamp {
+				c = 1
+			}
+		case ReceiveLargestFirst:
+			c = b.pending.Amount.Cmp(&a.pending.Amount.Int)
+		case ReceiveSmallestFirst:
+			c = a.pending.Amount.Cmp(&b.pending.Amount.Int)
+		}
+		if c == 0 {
+			return bytes.Compare(a.link, b.link) < 0
+		}
+		return c < 0
+	})
+}
+
+// pendingBlocks returns the pending blocks accepted by the receive
+// policy, in the order they should be received.
+func (w *Wallet) pendingBlocks(pendings rpc.HashToPendingMap) (blocks []pendingBlock, err error) {
+	p := &w.ReceivePolicy
+	if blocks, err = p.filter(pendings); err != nil || len(blocks) == 0 {
+		return
+	}
+	if p.Order == ReceiveOldestFirst {
+		hashes := make([]rpc.BlockHash, len(blocks))
+		for i, b := range blocks {
+			hashes[i] = b.link
+		}
+		// If the node can't tell us when it saw the blocks, fall back
+		// to ordering them by hash.
+		if info, err := w.RPC.BlocksInfo(hashes); err == nil {
+			timestamps := make(map[string]uint64, len(info))
+			for hash, info := range info {
+				timestamps[strings.ToUpper(hash)] = info.LocalTimestamp
+			}
+			for i, b := range blocks {
+				blocks[i].timestamp = timestamps[b.link.String()]
+			}
+		}
+	}
+	p.sort(blocks)
+	return
+}
+
+// limit truncates blocks to at most n, if n is positive.
+func limit(blocks []pendingBlock, n int) []pendingBlock {
+	if n > 0 && len(blocks) > n {
+		return blocks[:n]
+	}
+	return blocks
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceivePolicy(t *testing.T) {
+	const (
+		alice = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+		bob   = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	)
+	pending := func(amount int64, source string) rpc.AccountPending {
+		return rpc.AccountPending{Amount: &rpc.RawAmount{Int: *big.NewInt(amount)}, Source: source}
+	}
+	pendings := rpc.HashToPendingMap{
+		"01": pending(1, alice),
+		"02": pending(50, alice),
+		"03": pending(70, bob),
+		"04": pending(60, alice),
+	}
+	p := ReceivePolicy{MinAmount: big.NewInt(10), Order: ReceiveLargestFirst}
+	blocks, err := p.filter(pendings)
+	require.Nil(t, err)
+	p.sort(blocks)
+	var amounts []int64
+	for _, b := range blocks {
+		amounts = append(amounts, b.pending.Amount.Int64())
+	}
+	assert.Equal(t, []int64{70, 60, 50}, amounts)
+	assert.Len(t, limit(blocks, 2), 2)
+	assert.Len(t, limit(blocks, 0), 3)
+
+	p.Deny = []string{bob}
+	blocks, err = p.filter(pendings)
+	require.Nil(t, err)
+	assert.Len(t, blocks, 2)
+	p.Deny, p.Allow = nil, []string{bob}
+	blocks, err = p.filter(pendings)
+	require.Nil(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, rpc.BlockHash{3}, blocks[0].link)
+}
+
+func TestReceivePendingsOrder(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	// Funded out of hash order, so receiving oldest first must consult
+	// the node's timestamps.
+	n.fund(a.Address(), 10, 3)
+	n.fund(a.Address(), 30, 1)
+	n.fund(a.Address(), 20, 2)
+	n.fund(b.Address(), 40, 4)
+	n.reject = n.fund(a.Address(), 50, 5)
+	results, err := w.ReceivePendings()
+	require.Nil(t, err)
+	var amounts []int64
+	for _, r := range results {
+		amounts = append(amounts, r.Amount.Int64())
+	}
+	assert.Equal(t, []int64{10, 30, 20, 50, 40}, amounts)
+	for i, r := range results {
+		if i == 3 {
+			assert.NotNil(t, r.Err)
+			assert.Nil(t, r.Hash)
+		} else {
+			assert.Nil(t, r.Err)
+			assert.NotNil(t, r.Hash)
+		}
+	}
+	balance, pending, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(60), balance.Int64())
+	assert.Equal(t, int64(50), pending.Int64())
+
+	// The failed block is retried, largest first.
+	n.reject = nil
+	n.fund(a.Address(), 5, 6)
+	w.ReceivePolicy.Order = ReceiveLargestFirst
+	results, err = a.ReceivePendings()
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(50), results[0].Amount.Int64())
+	assert.Equal(t, int64(5), results[1].Amount.Int64())
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+}
@@ -0,0 +1,185 @@
+package wallet
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/websocket"
+)
+
+// Receiver automatically receives incoming sends to a wallet's accounts
+// which are accepted by the wallet's receive policy. Confirmations are
+// watched over the websocket at WebsocketURL, and the node is polled
+// every PollInterval in case any are missed.
+type Receiver struct {
+	Wallet       *Wallet
+	WebsocketURL string
+	PollInterval time.Duration
+	// Events, if set, receives the result for every block received or
+	// failed to be received, as well as errors in tracking the state of
+	// an account, which have no Link if no block is involved.
+	Events   chan<- ReceiveResult
+	received map[string]bool
+	mu       sync.Mutex
+	ws       *websocket.Client
+}
+
+const reconnectInterval = 10 * time.Second
+
+// Run receives pending blocks until ctx is done, returning its error.
+// Other errors are reported on Events and do not stop it.
+func (r *Receiver) Run(ctx context.Context) (err error) {
+	var (
+		messages  <-chan interface{}
+		reconnect <-chan time.Time
+	)
+	connect := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		ws := &websocket.Client{URL: r.WebsocketURL, Filter: true, Ctx: ctx}
+		for _, a := range r.Wallet.GetAccounts() {
+			ws.Accounts = append(ws.Accounts, a.address)
+		}
+		if err := ws.Connect(); err != nil {
+			messages = nil
+			reconnect = time.After(reconnectInterval)
+			return
+		}
+		r.ws, messages, reconnect = ws, ws.Messages, nil
+	}
+	disconnect := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.ws != nil {
+			r.ws.Close()
+			r.ws = nil
+		}
+		messages = nil
+	}
+	if r.WebsocketURL != "" {
+		connect()
+	}
+	defer disconnect()
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	r.received = make(map[string]bool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	r.poll()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.poll()
+		case <-reconnect:
+			connect()
+		case m := <-messages:
+			switch m := m.(type) {
+			case *websocket.Confirmation:
+				r.confirmed(m)
+			case error:
+				disconnect()
+				reconnect = time.After(reconnectInterval)
+			}
+		}
+	}
+}
+
+// Watch starts watching accounts added to the wallet after Run started.
+func (r *Receiver) Watch(accounts ...*Account) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ws == nil {
+		// The accounts will be included when the websocket connects.
+		return
+	}
+	addresses := make([]string, len(accounts))
+	for i, a := range accounts {
+		addresses[i] = a.address
+	}
+	return r.ws.AddAccounts(addresses)
+}
+
+func (r *Receiver) emit(e ReceiveResult) {
+	if r.Events != nil {
+		r.Events <- e
+	}
+}
+
+// poll receives all pending blocks known to the node. Failures are
+// reported on Events and retried on the next poll.
+func (r *Receiver) poll() {
+	accounts := r.Wallet.sortedAccounts()
+	addresses := make([]string, len(accounts))
+	for i, a := range accounts {
+		addresses[i] = a.address
+	}
+	pendings, err := r.Wallet.RPC.AccountsPending(addresses, -1)
+	if err != nil {
+		// The node may be temporarily unreachable; try again next time.
+		return
+	}
+	r.received = make(map[string]bool)
+	for _, a := range accounts {
+		a.mu.Lock()
+		err = a.setReceivable(pendings[a.address])
+		a.mu.Unlock()
+		if err != nil {
+			r.emit(ReceiveResult{Account: a.address, Err: err})
+		}
+	}
+	max := r.Wallet.ReceivePolicy.MaxBlocks
+	for _, a := range accounts {
+		blocks, err := r.Wallet.pendingBlocks(pendings[a.address])
+		if err != nil {
+			r.emit(ReceiveResult{Account: a.address, Err: err})
+			continue
+		}
+		blocks = limit(blocks, max)
+		for _, b := range blocks {
+			r.receive(a, b.link, &b.pending.Amount.Int)
+		}
+		if max > 0 {
+			if max -= len(blocks); max == 0 {
+				break
+			}
+		}
+	}
+}
+
+// confirmed receives the confirmed block if it is a send to the wallet.
+func (r *Receiver) confirmed(c *websocket.Confirmation) {
+	if err := r.Wallet.HandleConfirmation(c); err != nil {
+		r.emit(ReceiveResult{Account: c.Account, Link: c.Hash, Err: err})
+	}
+	if c.Block == nil || c.Block.Subtype != "send" || c.Amount == nil {
+		return
+	}
+	dest, err := util.PubkeyToAddress(c.Block.Link)
+	if err != nil {
+		return
+	}
+	if a := r.Wallet.GetAccount(dest); a != nil && r.Wallet.ReceivePolicy.Accepts(c.Account, &c.Amount.Int) {
+		r.receive(a, c.Hash, &c.Amount.Int)
+	}
+	return
+}
+
+func (r *Receiver) receive(a *Account, link rpc.BlockHash, amount *big.Int) {
+	// A block may be seen both by polling and over the websocket.
+	if r.received[link.String()] {
+		return
+	}
+	hash, err := a.ReceivePending(link)
+	if err == nil {
+		r.received[link.String()] = true
+	}
+	r.emit(ReceiveResult{Account: a.address, Link: link, Amount: amount, Hash: hash, Err: err})
+}
@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiver(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	n.fund(a.Address(), 5, 2)
+	events := make(chan ReceiveResult)
+	w.ReceivePolicy.MinAmount = big.NewInt(10)
+	r := &Receiver{Wallet: w, PollInterval: time.Hour, Events: events}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	e := <-events
+	require.Nil(t, e.Err)
+	assert.Equal(t, int64(100), e.Amount.Int64())
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	balance, pending, err := a.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), balance.Int64())
+	assert.Equal(t, int64(5), pending.Int64())
+
+	// A confirmed send to the account is received straight away,
+	// and a duplicate confirmation is ignored.
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	block, err := a.SendBlock(b.Address(), big.NewInt(50))
+	require.Nil(t, err)
+	hash, err := a.publishSend(&Payment{Account: b.Address(), Amount: big.NewInt(50)}, block)
+	require.Nil(t, err)
+	block.Subtype = "send"
+	c := &websocket.Confirmation{
+		Account: a.Address(),
+		Amount:  &rpc.RawAmount{Int: *big.NewInt(50)},
+		Hash:    hash,
+		Block:   block,
+	}
+	go func() {
+		r.confirmed(c)
+		r.confirmed(c)
+		close(events)
+	}()
+	e = <-events
+	require.Nil(t, e.Err)
+	assert.Equal(t, b.Address(), e.Account)
+	_, ok := <-events
+	assert.False(t, ok)
+	balance, _, err = b.Balance()
+	require.Nil(t, err)
+	assert.Equal(t, int64(50), balance.Int64())
+}
+
+// unsavableStore fails to save the state of one account.
+type unsavableStore struct {
+	Store
+	fail string
+}
+
+func (s *unsavableStore) Save(account string, st *AccountState) error {
+	if account == s.fail {
+		return errors.New("store unavailable")
+	}
+	return s.Store.Save(account, st)
+}
+
+func TestReceiverErrors(t *testing.T) {
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	dir, err := ioutil.TempDir("", "gonano")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(filepath.Join(dir, "state.json"))
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	w.Store = &unsavableStore{Store: store, fail: a.Address()}
+	n.fund(a.Address(), 100, 1)
+	n.fund(b.Address(), 100, 2)
+	events := make(chan ReceiveResult)
+	r := &Receiver{Wallet: w, PollInterval: time.Hour, Events: events}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	// A failure for one account is reported without stopping the rest.
+	var failed, received bool
+	for !failed || !received {
+		select {
+		case e := <-events:
+			if e.Account == a.Address() {
+				assert.NotNil(t, e.Err)
+				failed = true
+			} else {
+				assert.Nil(t, e.Err)
+				received = true
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+	}
+	cancel()
+	go func() {
+		for range events {
+		}
+	}()
+	assert.Equal(t, context.Canceled, <-done)
+	close(events)
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+)
+
+// DefaultRepresentative is the representative new accounts are opened
+// with when the wallet has none configured.
+const DefaultRepresentative = "nano_3gonano8jnse4zm65jaiki9tk8ry4jtgc1smarinukho6fmbc45k3icsh6en"
+
+// ErrRepOffline is returned by CheckRep for a representative which has
+// not voted recently.
+var ErrRepOffline = errors.New("representative is offline")
+
+// RepChange reports the outcome of changing an account's representative.
+// Hash is nil if no block was needed.
+type RepChange struct {
+	Account string
+	Hash    rpc.BlockHash
+	Err     error
+}
+
+func (w *Wallet) defaultRep() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.Representative != "" {
+		return w.Representative
+	}
+	return DefaultRepresentative
+}
+
+// SetRepresentative sets the representative new accounts are opened
+// with. Unlike setting Representative, it is safe while the wallet is in
+// use.
+func (w *Wallet) SetRepresentative(representative string) {
+	w.mu.Lock()
+	w.Representative = representative
+	w.mu.Unlock()
+}
+
+// ChangeRep re-delegates every account in the wallet to representative,
+// in index order, and makes it the wallet's default if they all succeed.
+// Accounts already delegated to it are left alone, and unopened accounts
+// will open with it. A failure does not stop the rest; the outcome of
+// each is returned.
+func (w *Wallet) ChangeRep(representative string) (results []RepChange, err error) {
+	if _, err = util.AddressToPubkey(representative); err != nil {
+		return
+	}
+	failed := false
+	for _, a := range w.sortedAccounts() {
+		r := RepChange{Account: a.address}
+		a.mu.Lock()
+		st, err := a.state()
+		if err == nil && st.Frontier == nil {
+			a.representative = representative
+		}
+		a.mu.Unlock()
+		switch {
+		case err != nil:
+			r.Err = err
+		case st.Frontier == nil || st.Representative == representative:
+		default:
+			r.Hash, r.Err = a.ChangeRep(representative)
+		}
+		if r.Err != nil {
+			failed = true
+		}
+		results = append(results, r)
+	}
+	if !failed {
+		w.SetRepresentative(representative)
+	}
+	return
+}
+
+// CheckRep checks that representative is online and holds no more than
+// maxShare (between 0 and 1) of the online voting weight, so that
+// delegating to it neither wastes the wallet's weight nor concentrates
+// the network's. A maxShare of 0 skips the weight check.
+func (w *Wallet) CheckRep(representative string, maxShare float64) (err error) {
+	reps, err := w.RPC.RepresentativesOnline()
+	if err != nil {
+		return
+	}
+	rep, ok := reps[representative]
+	if !ok {
+		return ErrRepOffline
+	}
+	if maxShare <= 0 || rep.Weight == nil {
+		return
+	}
+	total := new(big.Int)
+	for _, r := range reps {
+		if r.Weight != nil {
+			total.Add(total, &r.Weight.Int)
+		}
+	}
+	if total.Sign() == 0 {
+		return
+	}
+	share, _ := new(big.Rat).SetFrac(&rep.Weight.Int, total).Float64()
+	if share > maxShare {
+		err = fmt.Errorf("representative holds %.1f%% of online voting weight", share*100)
+	}
+	return
+}
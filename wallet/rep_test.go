@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeRep(t *testing.T) {
+	const rep = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	n.fund(a.Address(), 100, 1)
+	receiveAll(t, a)
+	assert.Equal(t, DefaultRepresentative, n.accounts[a.Address()].Representative)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+
+	results, err := w.ChangeRep(rep)
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	assert.Nil(t, results[0].Err)
+	assert.NotNil(t, results[0].Hash)
+	assert.Equal(t, rep, n.accounts[a.Address()].Representative)
+	assert.Equal(t, rep, w.Representative)
+	// The unopened account opens with the new representative.
+	assert.Nil(t, results[1].Hash)
+	n.fund(b.Address(), 10, 2)
+	receiveAll(t, b)
+	assert.Equal(t, rep, n.accounts[b.Address()].Representative)
+
+	// Nothing to do the second time round.
+	results, err = w.ChangeRep(rep)
+	require.Nil(t, err)
+	for _, r := range results {
+		assert.Nil(t, r.Err)
+		assert.Nil(t, r.Hash)
+	}
+
+	// The default is kept if any account fails to change.
+	n.drop = 1
+	results, err = w.ChangeRep(DefaultRepresentative)
+	require.Nil(t, err)
+	assert.NotNil(t, results[0].Err)
+	assert.Equal(t, rep, w.Representative)
+}
+
+func TestCheckRep(t *testing.T) {
+	const (
+		rep1 = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+		rep2 = "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny"
+	)
+	n := newFakeNode()
+	defer n.Close()
+	w := newTestWallet(t, n)
+	weight := func(w int64) rpc.Representative {
+		return rpc.Representative{Weight: &rpc.RawAmount{Int: *big.NewInt(w)}}
+	}
+	n.reps = map[string]rpc.Representative{rep1: weight(80), rep2: weight(20)}
+	assert.Nil(t, w.CheckRep(rep2, 0.5))
+	assert.NotNil(t, w.CheckRep(rep1, 0.5))
+	assert.Nil(t, w.CheckRep(rep1, 0))
+	assert.Equal(t, ErrRepOffline, w.CheckRep(DefaultRepresentative, 0))
+}
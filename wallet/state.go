@@ -0,0 +1,175 @@
+package wallet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+)
+
+// AccountState is the locally known state of an account chain.
+type AccountState struct {
+	Frontier       rpc.BlockHash        `json:"frontier"`
+	Balance        *rpc.RawAmount       `json:"balance"`
+	Representative string               `json:"representative"`
+	Height         uint64               `json:"height"`
+	Receivable     rpc.HashToPendingMap `json:"receivable,omitempty"`
+}
+
+func (st *AccountState) copy() *AccountState {
+	st2 := *st
+	st2.Balance = &rpc.RawAmount{}
+	st2.Balance.Set(&st.Balance.Int)
+	if st.Receivable != nil {
+		st2.Receivable = make(rpc.HashToPendingMap, len(st.Receivable))
+		for k, v := range st.Receivable {
+			st2.Receivable[k] = v
+		}
+	}
+	return &st2
+}
+
+func (st *AccountState) receivable(hash string) *big.Int {
+	if p, ok := st.Receivable[hash]; ok {
+		return &p.Amount.Int
+	}
+	return nil
+}
+
+// Store persists account state between runs.
+type Store interface {
+	// Load returns the saved state of account, or nil if there is none.
+	Load(account string) (st *AccountState, err error)
+	// Save saves the state of account. A nil state removes it.
+	Save(account string, st *AccountState) (err error)
+}
+
+// FileStore is a Store backed by a JSON file. The file may be shared by
+// several processes: each save is made under a lock on a lock file beside
+// it, after reading the file again, so that no process overwrites the
+// updates of another.
+type FileStore struct {
+	path    string
+	mu      sync.Mutex
+	states  map[string]*AccountState
+	modTime time.Time
+	size    int64
+}
+
+// NewFileStore opens the store at path, creating it if necessary.
+func NewFileStore(path string) (s *FileStore, err error) {
+	s = &FileStore{path: path, states: make(map[string]*AccountState)}
+	err = s.read()
+	return
+}
+
+// read reads the file, if it exists.
+func (s *FileStore) read() (err error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return
+	}
+	states := make(map[string]*AccountState)
+	if err = json.Unmarshal(data, &states); err != nil {
+		return
+	}
+	s.states, s.modTime, s.size = states, fi.ModTime(), fi.Size()
+	return
+}
+
+// refresh reads the file again if another process has replaced it.
+func (s *FileStore) refresh() (err error) {
+	fi, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return
+	}
+	if fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return
+	}
+	return s.read()
+}
+
+// Load returns the saved state of account, or nil if there is none.
+func (s *FileStore) Load(account string) (st *AccountState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = s.refresh(); err != nil {
+		return
+	}
+	if st = s.states[account]; st != nil {
+		st = st.copy()
+	}
+	return
+}
+
+// Save saves the state of account. A nil state removes it.
+func (s *FileStore) Save(account string, st *AccountState) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer lock.Close()
+	if err = lockFile(lock); err != nil {
+		return
+	}
+	defer unlockFile(lock)
+	if err = s.read(); err != nil {
+		return
+	}
+	if st == nil {
+		delete(s.states, account)
+	} else {
+		s.states[account] = st.copy()
+	}
+	data, err := json.Marshal(s.states)
+	if err != nil {
+		return
+	}
+	if err = writeFileAtomic(s.path, data); err != nil {
+		return
+	}
+	if fi, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = fi.ModTime(), fi.Size()
+	}
+	return
+}
+
+func writeFileAtomic(path string, data []byte) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -1,26 +1,34 @@
 package wallet
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/hectorchu/gonano/rpc"
 	"github.com/hectorchu/gonano/util"
 )
 
-// Wallet represents a wallet.
+// Wallet represents a wallet. It is safe for concurrent use.
 type Wallet struct {
-	seed         []byte
-	isBip39      bool
+	mu           sync.Mutex
 	nextIndex    uint32
 	accounts     map[string]*Account
 	RPC, RPCWork rpc.Client
-	impl         interface {
-		deriveAccount(*Account) error
-		signBlock(*Account, *rpc.Block) error
-	}
+	// Store, if set, persists the tracked state of accounts.
+	Store Store
+	// Journal, if set, records sends before they are published.
+	Journal Journal
+	// ReceivePolicy determines which pending blocks are received.
+	ReceivePolicy ReceivePolicy
+	// Representative is the representative new accounts are opened
+	// with. If empty, DefaultRepresentative is used.
+	Representative string
+	signer         Signer
 }
 
 // NewWallet creates a new wallet.
 func NewWallet(seed []byte) (w *Wallet, err error) {
-	w = newWallet(seed)
+	w = newWallet(&seedSigner{seed: seed})
 	return
 }
 
@@ -30,25 +38,34 @@ func NewBip39Wallet(mnemonic, password string) (w *Wallet, err error) {
 	if err != nil {
 		return
 	}
-	w = newWallet(seed)
-	w.isBip39 = true
+	w = newWallet(&seedSigner{seed: seed, isBip39: true})
 	return
 }
 
 // NewLedgerWallet creates a new Ledger wallet.
 func NewLedgerWallet() (w *Wallet, err error) {
 	w = newWallet(nil)
-	w.impl = ledgerImpl{}
+	w.signer = &ledgerSigner{w: w}
+	return
+}
+
+// NewSignerWallet creates a new wallet whose keys are managed by signer.
+func NewSignerWallet(signer Signer) (w *Wallet, err error) {
+	w = newWallet(signer)
 	return
 }
 
-func newWallet(seed []byte) *Wallet {
+// Signer returns the signer which manages the wallet's keys.
+func (w *Wallet) Signer() Signer {
+	return w.signer
+}
+
+func newWallet(signer Signer) *Wallet {
 	return &Wallet{
-		seed:     seed,
 		accounts: make(map[string]*Account),
 		RPC:      rpc.Client{URL: "https://mynano.ninja/api/node"},
 		RPCWork:  rpc.Client{URL: "http://[::1]:7076"},
-		impl:     seedImpl{},
+		signer:   signer,
 	}
 }
 
@@ -70,6 +87,7 @@ func (w *Wallet) ScanForAccounts() (err error) {
 	if err != nil {
 		return
 	}
+	w.mu.Lock()
 	i := len(accounts) - 1
 	for ; i >= 0; i-- {
 		if balances[accounts[i]].Pending.Sign() > 0 {
@@ -81,43 +99,53 @@ func (w *Wallet) ScanForAccounts() (err error) {
 		w.nextIndex = w.accounts[accounts[i]].index
 		delete(w.accounts, accounts[i])
 	}
+	w.mu.Unlock()
 	if i < 5 {
 		return
 	}
 	return w.ScanForAccounts()
 }
 
-// NewAccount creates a new account.
+// NewAccount creates a new account. If the account with the given index
+// is already known to the wallet, that account is returned.
 func (w *Wallet) NewAccount(index *uint32) (a *Account, err error) {
-	index2 := w.nextIndex
-	if index != nil {
-		index2 = *index
-	}
-	a = &Account{w: w, index: index2}
-	if err = w.impl.deriveAccount(a); err != nil {
-		return
-	}
-	if a.address, err = util.PubkeyToAddress(a.pubkey); err != nil {
-		return
-	}
-	if index == nil {
-		w.nextIndex++
-	}
-	if _, ok := w.accounts[a.address]; !ok {
-		w.accounts[a.address] = a
-	} else if index == nil {
-		return w.NewAccount(nil)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		index2 := w.nextIndex
+		if index != nil {
+			index2 = *index
+		}
+		a = &Account{w: w, index: index2}
+		if a.pubkey, err = w.signer.Pubkey(a.index); err != nil {
+			return
+		}
+		if a.address, err = util.PubkeyToAddress(a.pubkey); err != nil {
+			return
+		}
+		if index == nil {
+			w.nextIndex++
+		}
+		if a2, ok := w.accounts[a.address]; !ok {
+			w.accounts[a.address] = a
+			return
+		} else if index != nil {
+			return a2, nil
+		}
 	}
-	return
 }
 
 // GetAccount gets the account with address or nil if not found.
 func (w *Wallet) GetAccount(address string) *Account {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.accounts[address]
 }
 
 // GetAccounts gets all the accounts in the wallet.
 func (w *Wallet) GetAccounts() (accounts []*Account) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	accounts = make([]*Account, 0, len(w.accounts))
 	for _, account := range w.accounts {
 		accounts = append(accounts, account)
@@ -125,20 +153,41 @@ func (w *Wallet) GetAccounts() (accounts []*Account) {
 	return
 }
 
-// ReceivePendings pockets all pending amounts.
-func (w *Wallet) ReceivePendings() (err error) {
-	accounts := make([]string, 0, len(w.accounts))
-	for address := range w.accounts {
-		accounts = append(accounts, address)
-	}
-	pendings, err := w.RPC.AccountsPending(accounts, -1)
+// ReceivePendings pockets the pending amounts accepted by the receive policy.
+// Accounts are visited in index order and each account's blocks in the
+// policy's order. A block which fails to be received does not stop the
+// rest; the outcome of each is returned.
+func (w *Wallet) ReceivePendings() (results []ReceiveResult, err error) {
+	accounts := w.sortedAccounts()
+	addresses := make([]string, len(accounts))
+	for i, a := range accounts {
+		addresses[i] = a.address
+	}
+	pendings, err := w.RPC.AccountsPending(addresses, -1)
 	if err != nil {
 		return
 	}
-	for account, pendings := range pendings {
-		if err = w.accounts[account].receivePendings(pendings); err != nil {
-			return
+	max := w.ReceivePolicy.MaxBlocks
+	for _, a := range accounts {
+		r, err := a.receivePendings(pendings[a.address], max)
+		results = append(results, r...)
+		if err != nil {
+			return results, err
+		}
+		if max > 0 {
+			if max -= len(r); max == 0 {
+				break
+			}
 		}
 	}
 	return
 }
+
+// sortedAccounts gets all the accounts in the wallet in index order.
+func (w *Wallet) sortedAccounts() (accounts []*Account) {
+	accounts = w.GetAccounts()
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].index < accounts[j].index
+	})
+	return
+}
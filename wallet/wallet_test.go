@@ -0,0 +1,31 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSigner struct{ seedSigner }
+
+func TestNewSignerWallet(t *testing.T) {
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewSignerWallet(&testSigner{seedSigner{seed: seed}})
+	require.Nil(t, err)
+	index := uint32(1)
+	a, err := w.NewAccount(&index)
+	require.Nil(t, err)
+	assert.Equal(t, "nano_35s8xxbrurpph5zrcb8ey3y1j9niij7k1m645otcxdk3fxg517i6j5empshy", a.Address())
+	block := &rpc.Block{
+		Account:        a.Address(),
+		Previous:       make(rpc.BlockHash, 32),
+		Representative: a.Address(),
+		Balance:        &rpc.RawAmount{},
+		Link:           make(rpc.BlockHash, 32),
+	}
+	require.Nil(t, w.signer.SignBlock(a.Index(), block))
+	assert.Len(t, block.Signature, 64)
+}
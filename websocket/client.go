@@ -6,9 +6,13 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// Client is used for connecting to websocket endpoints.
+// Client is used for connecting to websocket endpoints. If Accounts is
+// non-empty or Filter is set, only confirmations involving those accounts
+// are received.
 type Client struct {
 	URL      string
+	Accounts []string
+	Filter   bool
 	Ctx      context.Context
 	c        *websocket.Conn
 	Messages chan interface{}
@@ -23,10 +27,18 @@ func (c *Client) Connect() (err error) {
 	if c.c, _, err = websocket.DefaultDialer.DialContext(c.Ctx, c.URL, nil); err != nil {
 		return
 	}
-	if err = c.c.WriteJSON(map[string]string{
+	subscribe := map[string]interface{}{
 		"action": "subscribe",
 		"topic":  "confirmation",
-	}); err != nil {
+	}
+	if len(c.Accounts) > 0 || c.Filter {
+		accounts := c.Accounts
+		if accounts == nil {
+			accounts = []string{}
+		}
+		subscribe["options"] = map[string]interface{}{"accounts": accounts}
+	}
+	if err = c.c.WriteJSON(subscribe); err != nil {
 		c.c.Close()
 		return
 	}
@@ -36,6 +48,15 @@ func (c *Client) Connect() (err error) {
 	return
 }
 
+// AddAccounts adds accounts to those whose confirmations are received.
+func (c *Client) AddAccounts(accounts []string) (err error) {
+	return c.c.WriteJSON(map[string]interface{}{
+		"action":  "update",
+		"topic":   "confirmation",
+		"options": map[string]interface{}{"accounts_add": accounts},
+	})
+}
+
 // Close closes the connection.
 func (c *Client) Close() (err error) {
 	err = c.c.Close()